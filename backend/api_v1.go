@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Коды ошибок /v1 (поле code в APIError)
+const (
+	codeBadRequest           = "bad_request"
+	codeInvalidParameter     = "invalid_parameter"
+	codeUnauthorized         = "unauthorized"
+	codeForbidden            = "forbidden"
+	codeNotFound             = "not_found"
+	codeMethodNotAllowed     = "method_not_allowed"
+	codeNotAcceptable        = "not_acceptable"
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codeQuotaExceeded        = "quota_exceeded"
+	codeInternal             = "internal_error"
+	codeUnavailable          = "unavailable"
+)
+
+// apiErrorCodes — все коды; gosearch openapi check сверяет их с перечислением в openapi.json
+var apiErrorCodes = []string{
+	codeBadRequest, codeInvalidParameter, codeUnauthorized, codeForbidden, codeNotFound, codeMethodNotAllowed,
+	codeNotAcceptable, codeUnsupportedMediaType, codeQuotaExceeded, codeInternal, codeUnavailable,
+}
+
+// statusErrorCodes — код ошибки для ответов, которые обработчики пишут через http.Error
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:           codeBadRequest,
+	http.StatusUnauthorized:         codeUnauthorized,
+	http.StatusForbidden:            codeForbidden,
+	http.StatusNotFound:             codeNotFound,
+	http.StatusMethodNotAllowed:     codeMethodNotAllowed,
+	http.StatusNotAcceptable:        codeNotAcceptable,
+	http.StatusUnsupportedMediaType: codeUnsupportedMediaType,
+	http.StatusTooManyRequests:      codeQuotaExceeded,
+	http.StatusInternalServerError:  codeInternal,
+	http.StatusServiceUnavailable:   codeUnavailable,
+}
+
+// APIError — тело любого ответа /v1 с ошибкой
+type APIError struct {
+	Code    string                 `json:"code"`    // машиночитаемый код, см. константы code*
+	Message string                 `json:"message"` // описание для разработчика, на английском
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// v1Route — эндпоинт /v1 и типы, о которых договариваемся с клиентом
+type v1Route struct {
+	path     string // путь после /v1; с "/" на конце — префикс
+	handler  http.Handler
+	produces []string // типы ответа в порядке предпочтения
+	consumes []string // типы тела POST; пусто — не проверяем
+}
+
+// v1Routes — публичный API; админские эндпоинты остаются только без версии
+var v1Routes = []v1Route{
+	{
+		path:     "/search",
+		handler:  authenticate(ScopeSearch, enforceQuota(http.HandlerFunc(handleSearch))),
+		produces: []string{"application/json"},
+		consumes: []string{"application/json", "application/x-www-form-urlencoded"},
+	},
+	{
+		path:     "/search/batch",
+		handler:  authenticate(ScopeSearch, http.HandlerFunc(handleSearchBatch)),
+		produces: []string{"application/json", ndjsonContentType},
+		consumes: []string{"application/json", "text/csv", "multipart/form-data"},
+	},
+	{
+		path:     "/breaches/range/",
+		handler:  authenticate(ScopeBreaches, http.HandlerFunc(handleBreachRange)),
+		produces: []string{"application/json"},
+	},
+	{
+		path:     "/export",
+		handler:  authenticate(ScopeExport, http.HandlerFunc(handleExport)),
+		produces: []string{"application/json"},
+	},
+	{
+		path:     "/openapi.json",
+		handler:  http.HandlerFunc(handleOpenAPI),
+		produces: []string{"application/json", "application/vnd.oai.openapi+json"},
+	},
+}
+
+// handleV1 обслуживает /v1/...: те же обработчики, что и без версии, но с согласованием
+// Accept/Content-Type и ошибками в виде APIError
+func handleV1(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+	route, ok := findV1Route(path)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, codeNotFound, "No such endpoint: "+r.URL.Path, nil)
+		return
+	}
+
+	w.Header().Set("Vary", "Accept")
+	mediaType, explicit, ok := negotiate(r.Header.Get("Accept"), route.produces)
+	if !ok {
+		writeAPIError(w, http.StatusNotAcceptable, codeNotAcceptable, "None of the types in Accept can be produced",
+			map[string]interface{}{"supported": route.produces})
+		return
+	}
+	if r.Method == "POST" && len(route.consumes) > 0 {
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if !containsString(route.consumes, contentType) {
+			writeAPIError(w, http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "Unsupported request body type: "+strconv.Quote(contentType),
+				map[string]interface{}{"supported": route.consumes})
+			return
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), apiVersionKey, "v1")
+	if explicit {
+		ctx = context.WithValue(ctx, mediaTypeKey, mediaType)
+	}
+	r = r.WithContext(ctx)
+	// Обработчики разбирают путь без префикса версии (например, /breaches/range/{prefix})
+	u := *r.URL
+	u.Path, u.RawPath = path, ""
+	r.URL = &u
+
+	writer := &v1Writer{ResponseWriter: w}
+	route.handler.ServeHTTP(writer, r)
+	writer.finish()
+}
+
+func findV1Route(path string) (v1Route, bool) {
+	for _, route := range v1Routes {
+		if path == route.path || strings.HasSuffix(route.path, "/") && strings.HasPrefix(path, route.path) {
+			return route, true
+		}
+	}
+	return v1Route{}, false
+}
+
+// isV1 — запрос пришел через /v1 и ошибки нужно отдавать как APIError
+func isV1(r *http.Request) bool {
+	version, _ := r.Context().Value(apiVersionKey).(string)
+	return version == "v1"
+}
+
+// apiSearchResult убирает из результата текст для людей: в /v1 «ничего не найдено» — пустой found_on
+func apiSearchResult(r *http.Request, result SearchResult) SearchResult {
+	if isV1(r) {
+		result.Error = ""
+	}
+	return result
+}
+
+// negotiatedType — тип ответа, который клиент явно назвал в Accept; пусто — решает обработчик
+func negotiatedType(r *http.Request) string {
+	mediaType, _ := r.Context().Value(mediaTypeKey).(string)
+	return mediaType
+}
+
+// writeAPIError отвечает ошибкой в формате /v1
+func writeAPIError(w http.ResponseWriter, status int, code, message string, details map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("X-Content-Type-Options")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIError{Code: code, Message: message, Details: details})
+}
+
+// v1Writer превращает текстовые ответы http.Error в APIError, чтобы обработчики
+// не знали о версии API; остальные ответы проходят как есть
+type v1Writer struct {
+	http.ResponseWriter
+	status int
+	plain  *bytes.Buffer // текст ошибки, пока обработчик его пишет
+}
+
+func (w *v1Writer) WriteHeader(status int) {
+	if status >= 400 && strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		w.status, w.plain = status, &bytes.Buffer{}
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *v1Writer) Write(p []byte) (int, error) {
+	if w.plain != nil {
+		return w.plain.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush нужен потоку NDJSON из /search/batch
+func (w *v1Writer) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok && w.plain == nil {
+		flusher.Flush()
+	}
+}
+
+func (w *v1Writer) finish() {
+	if w.plain == nil {
+		return
+	}
+	// Редкие статусы без своего кода сводим к общим, чтобы code всегда был из apiErrorCodes
+	code, ok := statusErrorCodes[w.status]
+	if !ok {
+		code = codeBadRequest
+		if w.status >= 500 {
+			code = codeInternal
+		}
+	}
+	writeAPIError(w.ResponseWriter, w.status, code, strings.TrimSpace(w.plain.String()), nil)
+}
+
+// negotiate выбирает из offers тип ответа по Accept с учетом q (RFC 9110, 12.5.1).
+// explicit — клиент назвал тип прямо, а не через */* или type/*.
+func negotiate(accept string, offers []string) (mediaType string, explicit, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], false, true
+	}
+	best := 0.0
+	for _, offer := range offers {
+		// Для каждого предложения берем q самого точного подходящего диапазона
+		q, specificity := 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			mediaRange, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			s := matchMediaRange(mediaRange, offer)
+			if s <= specificity {
+				continue
+			}
+			q, specificity = 1.0, s
+			if value, ok := params["q"]; ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > best {
+			best, mediaType, explicit = q, offer, specificity == 2
+		}
+	}
+	return mediaType, explicit, best > 0
+}
+
+// matchMediaRange — насколько точно диапазон из Accept подходит типу: 2 — совпадает,
+// 1 — type/*, 0 — */*, -1 — не подходит
+func matchMediaRange(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	}
+	return -1
+}
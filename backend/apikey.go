@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Области действия API-ключей
+const (
+	ScopeSearch   = "search"
+	ScopeAdmin    = "admin"
+	ScopeExport   = "export"
+	ScopeBreaches = "breaches" // диапазонные запросы к индексу утечек
+)
+
+// Префикс, по которому ключи легко узнать в логах и секретах
+const apiKeyPrefix = "gsk_"
+
+// APIKey — запись о ключе; сам ключ не хранится, только его SHA-256
+type APIKey struct {
+	ID     string   `json:"id"`
+	Hash   string   `json:"hash"` // "sha256:<hex>"
+	Scopes []string `json:"scopes"`
+	Owner  string   `json:"owner,omitempty"` // команда, на которую относим использование
+	Tier   string   `json:"tier,omitempty"`  // уровень квоты из QUOTA_TIERS
+}
+
+// HasScope проверяет, разрешена ли ключу область scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HashAPIKey возвращает значение для поля hash
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey создает новый случайный ключ
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+var (
+	apiKeysOnce sync.Once
+	apiKeys     map[string]*APIKey // hash -> ключ
+)
+
+// loadAPIKeys читает записи из файла API_KEYS_FILE или JSON-массива в API_KEYS
+func loadAPIKeys() map[string]*APIKey {
+	data := []byte(os.Getenv("API_KEYS"))
+	if path := os.Getenv("API_KEYS_FILE"); path != "" {
+		fileData, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Error reading API_KEYS_FILE: %v", err)
+			return map[string]*APIKey{}
+		}
+		data = fileData
+	}
+
+	keys := map[string]*APIKey{}
+	if len(data) == 0 {
+		return keys
+	}
+	var records []*APIKey
+	if err := json.Unmarshal(data, &records); err != nil {
+		log.Printf("Error parsing API keys: %v", err)
+		return keys
+	}
+	for _, record := range records {
+		if record.ID == "" || !strings.HasPrefix(record.Hash, "sha256:") {
+			log.Printf("Skipping API key record without id or sha256 hash: %q", record.ID)
+			continue
+		}
+		keys[record.Hash] = record
+	}
+	log.Printf("Loaded %d API keys", len(keys))
+	return keys
+}
+
+// lookupAPIKey находит запись по предъявленному ключу
+func lookupAPIKey(key string) (*APIKey, bool) {
+	apiKeysOnce.Do(func() { apiKeys = loadAPIKeys() })
+	record, ok := apiKeys[HashAPIKey(key)]
+	return record, ok
+}
+
+// APIKeyFromContext возвращает ключ, которым аутентифицирован запрос
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyKey).(*APIKey)
+	return key, ok && key != nil
+}
+
+// authenticate пускает запросы с API-ключом нужной области (Authorization: Bearer gsk_...).
+// Без заголовка поиск доступен Mini App через initData, остальные области — только по ключу.
+func authenticate(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if authorization == "" {
+			if scope == ScopeSearch {
+				requireTelegramAuth(next).ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, "Unauthorized: API key is required", http.StatusUnauthorized)
+			return
+		}
+
+		presented, ok := strings.CutPrefix(authorization, "Bearer ")
+		if !ok {
+			http.Error(w, "Unauthorized: expected Bearer API key", http.StatusUnauthorized)
+			return
+		}
+		key, ok := lookupAPIKey(strings.TrimSpace(presented))
+		if !ok {
+			log.Printf("Rejected request to %s: unknown API key", r.URL.Path)
+			http.Error(w, "Unauthorized: invalid API key", http.StatusUnauthorized)
+			return
+		}
+		if !key.HasScope(scope) {
+			http.Error(w, "Forbidden: API key lacks scope "+scope, http.StatusForbidden)
+			return
+		}
+
+		recordAPIKeyUsage(key, 1, 0)
+		ctx := context.WithValue(r.Context(), apiKeyKey, key)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// APIKeyUsage — счетчики использования одного ключа для распределения затрат
+type APIKeyUsage struct {
+	ID           string    `json:"id"`
+	Owner        string    `json:"owner,omitempty"`
+	Requests     int64     `json:"requests"`
+	SitesChecked int64     `json:"sites_checked"` // исходящие проверки — основная статья затрат
+	LastUsed     time.Time `json:"last_used"`
+}
+
+// Счетчики живут в памяти процесса: на Vercel у каждого инстанса свои, и они
+// обнуляются при перезапуске. Для биллинга это оценка снизу, а не точный учет.
+var (
+	apiKeyUsageMu    sync.Mutex
+	apiKeyUsage      = map[string]*APIKeyUsage{}
+	apiKeyUsageSince = time.Now().UTC()
+)
+
+// APIKeyUsageReport — ответ /admin/usage: счетчики одного инстанса с момента его запуска
+type APIKeyUsageReport struct {
+	Scope    string        `json:"scope"` // всегда "instance"
+	Instance string        `json:"instance"`
+	Since    time.Time     `json:"since"`
+	Note     string        `json:"note"`
+	Keys     []APIKeyUsage `json:"keys"`
+}
+
+// recordAPIKeyUsage прибавляет запросы и выполненные проверки к счетчикам ключа
+func recordAPIKeyUsage(key *APIKey, requests, sitesChecked int) {
+	apiKeyUsageMu.Lock()
+	defer apiKeyUsageMu.Unlock()
+
+	usage, ok := apiKeyUsage[key.ID]
+	if !ok {
+		usage = &APIKeyUsage{ID: key.ID, Owner: key.Owner}
+		apiKeyUsage[key.ID] = usage
+	}
+	usage.Requests += int64(requests)
+	usage.SitesChecked += int64(sitesChecked)
+	usage.LastUsed = time.Now().UTC()
+}
+
+// recordSearchUsage относит выполненный поиск на ключ из контекста, если он есть
+func recordSearchUsage(ctx context.Context, sitesChecked int) {
+	if key, ok := APIKeyFromContext(ctx); ok {
+		recordAPIKeyUsage(key, 0, sitesChecked)
+		log.Printf("API key %s (%s): search checked %d sites", key.ID, key.Owner, sitesChecked)
+	}
+}
+
+// handleAdminUsage отдает счетчики использования по ключам (область admin) — только этого инстанса
+func handleAdminUsage(w http.ResponseWriter, r *http.Request) {
+	apiKeyUsageMu.Lock()
+	report := make([]APIKeyUsage, 0, len(apiKeyUsage))
+	for _, usage := range apiKeyUsage {
+		report = append(report, *usage)
+	}
+	apiKeyUsageMu.Unlock()
+
+	sort.Slice(report, func(i, j int) bool { return report[i].ID < report[j].ID })
+	instance, _ := os.Hostname()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIKeyUsageReport{
+		Scope:    "instance",
+		Instance: fmt.Sprintf("%s/%d", instance, os.Getpid()),
+		Since:    apiKeyUsageSince,
+		Note:     "Best-effort: counters are kept in memory of this instance only, reset on restart and not summed across instances",
+		Keys:     report,
+	})
+}
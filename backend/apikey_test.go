@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useAPIKeys подменяет загруженные ключи до конца теста
+func useAPIKeys(t *testing.T, keys map[string]*APIKey) {
+	t.Helper()
+	records := map[string]*APIKey{}
+	for key, record := range keys {
+		records[HashAPIKey(key)] = record
+	}
+	apiKeysOnce, apiKeys = sync.Once{}, nil
+	apiKeysOnce.Do(func() { apiKeys = records })
+	t.Cleanup(func() { apiKeysOnce, apiKeys = sync.Once{}, nil })
+}
+
+func apiKeyUsageOf(id string) APIKeyUsage {
+	apiKeyUsageMu.Lock()
+	defer apiKeyUsageMu.Unlock()
+	if usage := apiKeyUsage[id]; usage != nil {
+		return *usage
+	}
+	return APIKeyUsage{}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Setenv("TELEGRAM_BOT_TOKEN", testBotToken)
+	useAPIKeys(t, map[string]*APIKey{
+		"gsk_search": {ID: "auth-search", Owner: "osint", Scopes: []string{ScopeSearch}},
+		"gsk_admin":  {ID: "auth-admin", Owner: "ops", Scopes: []string{ScopeAdmin, ScopeExport}},
+	})
+	initData := signInitData(testBotToken, initDataFields(time.Now(), `{"id":42,"first_name":"Alice"}`))
+
+	tests := []struct {
+		name          string
+		scope         string
+		authorization string
+		initData      string
+		status        int
+		key           string // ID ключа в контексте; пусто — без ключа
+	}{
+		{"search key", ScopeSearch, "Bearer gsk_search", "", http.StatusOK, "auth-search"},
+		{"spaces around the key", ScopeSearch, "Bearer  gsk_search ", "", http.StatusOK, "auth-search"},
+		{"key with several scopes", ScopeExport, "Bearer gsk_admin", "", http.StatusOK, "auth-admin"},
+		{"not bearer", ScopeSearch, "Basic gsk_search", "", http.StatusUnauthorized, ""},
+		{"lowercase bearer", ScopeSearch, "bearer gsk_search", "", http.StatusUnauthorized, ""},
+		{"unknown key", ScopeSearch, "Bearer gsk_unknown", "", http.StatusUnauthorized, ""},
+		{"missing scope", ScopeAdmin, "Bearer gsk_search", "", http.StatusForbidden, ""},
+		// Без ключа поиск доступен Mini App по initData, остальные области — нет
+		{"initData for search", ScopeSearch, "", initData, http.StatusOK, ""},
+		{"initData for admin", ScopeAdmin, "", initData, http.StatusUnauthorized, ""},
+		{"initData for export", ScopeExport, "", initData, http.StatusUnauthorized, ""},
+		{"nothing for search", ScopeSearch, "", "", http.StatusUnauthorized, ""},
+		// Ключ важнее initData: неверный ключ не спасает подпись Mini App
+		{"unknown key with initData", ScopeSearch, "Bearer gsk_unknown", initData, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reached bool
+			var key *APIKey
+			handler := authenticate(tt.scope, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+				key, _ = APIKeyFromContext(r.Context())
+			}))
+			r := httptest.NewRequest("GET", "/search?username=alice", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.initData != "" {
+				r.Header.Set(initDataHeader, tt.initData)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if reached != (tt.status == http.StatusOK) {
+				t.Fatalf("handler reached = %v with status %d", reached, w.Code)
+			}
+			switch {
+			case tt.key == "" && key != nil:
+				t.Errorf("API key %s in context, want none", key.ID)
+			case tt.key != "" && (key == nil || key.ID != tt.key):
+				t.Errorf("API key in context %+v, want %s", key, tt.key)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRecordsUsage(t *testing.T) {
+	useAPIKeys(t, map[string]*APIKey{
+		"gsk_usage_a": {ID: "usage-a", Owner: "team-a", Scopes: []string{ScopeSearch}},
+		"gsk_usage_b": {ID: "usage-b", Owner: "team-b", Scopes: []string{ScopeSearch}},
+	})
+	beforeA, beforeB := apiKeyUsageOf("usage-a"), apiKeyUsageOf("usage-b")
+
+	handler := authenticate(ScopeSearch, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recordSearchUsage(r.Context(), 3)
+	}))
+	for _, authorization := range []string{"Bearer gsk_usage_a", "Bearer gsk_usage_a", "Bearer gsk_usage_b", "Bearer gsk_unknown"} {
+		r := httptest.NewRequest("GET", "/search?username=alice", nil)
+		r.Header.Set("Authorization", authorization)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	// Каждый ключ получает только свои запросы и проверки
+	a, b := apiKeyUsageOf("usage-a"), apiKeyUsageOf("usage-b")
+	if requests, sites := a.Requests-beforeA.Requests, a.SitesChecked-beforeA.SitesChecked; requests != 2 || sites != 6 {
+		t.Errorf("usage-a: +%d requests, +%d sites; want +2 and +6", requests, sites)
+	}
+	if requests, sites := b.Requests-beforeB.Requests, b.SitesChecked-beforeB.SitesChecked; requests != 1 || sites != 3 {
+		t.Errorf("usage-b: +%d requests, +%d sites; want +1 and +3", requests, sites)
+	}
+	if a.Owner != "team-a" || b.Owner != "team-b" {
+		t.Errorf("owners %q and %q, want team-a and team-b", a.Owner, b.Owner)
+	}
+}
@@ -0,0 +1,370 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxBatchBodySize = 4 << 20
+	// batchStreamThreshold — с какого размера пакета ответ идет NDJSON-потоком без Accept
+	batchStreamThreshold = 20
+	ndjsonContentType    = "application/x-ndjson"
+)
+
+// BatchMatrix — какие сайты нашлись у каких имен: строки — сайты, столбцы — имена
+type BatchMatrix struct {
+	Usernames []string         `json:"usernames"`
+	Sites     []BatchMatrixRow `json:"sites"` // только сайты хотя бы с одним найденным профилем
+}
+
+// BatchMatrixRow — сайт и найден ли он у каждого имени (по порядку Usernames)
+type BatchMatrixRow struct {
+	Site  string `json:"site"`
+	Hits  []bool `json:"hits"`
+	Count int    `json:"count"`
+}
+
+// BatchResponse — ответ /search/batch без потока
+type BatchResponse struct {
+	Results []SearchResult `json:"results"` // в порядке имен в запросе
+	Matrix  BatchMatrix    `json:"matrix"`
+}
+
+// BatchLine — строка NDJSON-потока: результаты по мере готовности, матрица последней строкой
+type BatchLine struct {
+	Type   string        `json:"type"` // result или matrix
+	Index  int           `json:"index"`
+	Result *SearchResult `json:"result,omitempty"`
+	Matrix *BatchMatrix  `json:"matrix,omitempty"`
+}
+
+// handleSearchBatch — POST /search/batch: JSON-массив имен, {"usernames": [...]} или CSV.
+// Фильтры те же, что у /search (в query, а для JSON — и в теле). Дневная квота списывается за каждое
+// имя, а вместо burst одиночных поисков пакеты ограничивает свой batch_burst.
+func handleSearchBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	usernames, params, err := batchRequest(r)
+	if err != nil {
+		http.Error(w, "Bad request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts, err := parseSearchOptions(params, loadSites())
+	if err != nil {
+		writeSearchParamError(w, r, err)
+		return
+	}
+	if usernames, err = batchQueries(usernames, opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, tierHint := quotaKey(r), quotaTierHint(r)
+	if _, tier := quotaTier(key, tierHint); tier.Daily > 0 && len(usernames) > tier.Daily {
+		// Такой пакет не пройдет и завтра: он больше всего дневного лимита
+		http.Error(w, fmt.Sprintf("too many usernames for the daily quota: %d, at most %d", len(usernames), tier.Daily), http.StatusBadRequest)
+		return
+	}
+	if exceeded := checkBatchQuota(key, tierHint, len(usernames)); exceeded != nil {
+		writeQuotaExceeded(w, r, key, exceeded)
+		return
+	}
+
+	token := os.Getenv("TELEGRAM_BOT_TOKEN")
+	if token == "" {
+		log.Println("Error: TELEGRAM_BOT_TOKEN environment variable not set")
+		http.Error(w, "Server configuration error", http.StatusInternalServerError)
+		return
+	}
+
+	stream := len(usernames) > batchStreamThreshold || acceptsNDJSON(r)
+	if mediaType := negotiatedType(r); mediaType != "" {
+		// В /v1 тип, явно названный в Accept, важнее размера пакета
+		stream = mediaType == ndjsonContentType
+	}
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	if stream {
+		w.Header().Set("Content-Type", ndjsonContentType)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	results := make([]SearchResult, len(usernames))
+	checked := 0
+	searchBatch(r.Context(), newBotAPI(token), usernames, opts, func(i int, result SearchResult) {
+		result = apiSearchResult(r, result)
+		results[i] = result
+		checked += result.TotalSitesChecked
+		if stream {
+			enc.Encode(BatchLine{Type: "result", Index: i, Result: &result})
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	})
+	recordSearchUsage(r.Context(), checked)
+
+	matrix := batchMatrix(usernames, results)
+	if stream {
+		enc.Encode(BatchLine{Type: "matrix", Matrix: &matrix})
+		return
+	}
+	enc.Encode(BatchResponse{Results: results, Matrix: matrix})
+}
+
+func acceptsNDJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), ndjsonContentType)
+}
+
+// batchRequest читает имена из тела; параметры поиска — из query и JSON-тела
+func batchRequest(r *http.Request) ([]string, url.Values, error) {
+	params := r.URL.Query()
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/json":
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxBatchBodySize))
+		if err != nil {
+			return nil, nil, err
+		}
+		// Голый массив имен или объект с usernames и фильтрами
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+			var list []string
+			if err := json.Unmarshal(trimmed, &list); err != nil {
+				return nil, nil, err
+			}
+			return list, params, nil
+		}
+		var body searchBody
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, nil, err
+		}
+		body.apply(params)
+		return params["usernames"], params, nil
+
+	case "text/csv":
+		list, err := csvUsernames(io.LimitReader(r.Body, maxBatchBodySize))
+		return list, params, err
+
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(maxBatchBodySize); err != nil {
+			return nil, nil, err
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, nil, fmt.Errorf("file: %w", err)
+		}
+		defer file.Close()
+		list, err := csvUsernames(file)
+		for key, values := range r.MultipartForm.Value {
+			params[key] = append(params[key], values...)
+		}
+		return list, params, err
+	}
+	return nil, nil, fmt.Errorf("unsupported content type %q: expected application/json, text/csv or multipart/form-data", mediaType)
+}
+
+// csvUsernames берет колонку username, если есть такой заголовок, иначе первую колонку
+func csvUsernames(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	column := 0
+	if len(rows) > 0 {
+		for i, cell := range rows[0] {
+			if name := strings.ToLower(strings.TrimSpace(cell)); name == "username" || name == "email" {
+				column = i
+				rows = rows[1:]
+				break
+			}
+		}
+	}
+	var list []string
+	for _, row := range rows {
+		if column < len(row) {
+			list = append(list, row[column])
+		}
+	}
+	return list, nil
+}
+
+// batchQueries чистит список: пробелы, "@" перед именем, пустые строки и повторы;
+// в режиме email проверяет адреса
+func batchQueries(list []string, opts SearchOptions) ([]string, error) {
+	var queries, invalid []string
+	seen := map[string]bool{}
+	for _, item := range list {
+		query := strings.TrimSpace(item)
+		if opts.Type == SearchTypeEmail {
+			email, err := parseEmail(query)
+			if err != nil {
+				invalid = append(invalid, query)
+				continue
+			}
+			query = email
+		} else {
+			query = strings.TrimPrefix(query, "@")
+		}
+		if query == "" || seen[strings.ToLower(query)] {
+			continue
+		}
+		seen[strings.ToLower(query)] = true
+		queries = append(queries, query)
+	}
+
+	switch max := Settings().Batch.MaxUsernames; {
+	case len(invalid) > 0:
+		return nil, fmt.Errorf("invalid email addresses: %s", strings.Join(invalid, ", "))
+	case len(queries) == 0:
+		return nil, fmt.Errorf("no usernames in request")
+	case len(queries) > max:
+		return nil, fmt.Errorf("too many usernames: %d, at most %d per batch", len(queries), max)
+	}
+	return queries, nil
+}
+
+// batchSearch — накопленные результаты одного имени
+type batchSearch struct {
+	plan     *searchPlan
+	mu       sync.Mutex
+	pending  int
+	telegram *TelegramResult
+	found    []SiteResult
+	breaches breachAnswer
+}
+
+// finish отмечает выполненную задачу; true — это была последняя задача имени
+func (s *batchSearch) finish(update func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update()
+	s.pending--
+	return s.pending == 0
+}
+
+// searchBatch ищет все имена через общий пул из batch.concurrency воркеров, чтобы
+// число одновременных запросов не росло с размером пакета. done вызывается по мере
+// готовности имен, всегда из одной горутины.
+func searchBatch(parent context.Context, bot *botAPI, queries []string, opts SearchOptions, done func(i int, result SearchResult)) {
+	cfg := Settings()
+	ctx, cancel := context.WithTimeout(parent, time.Duration(cfg.Batch.Timeout))
+	defer cancel()
+	client := &http.Client{
+		Timeout:   time.Duration(cfg.Search.ClientTimeout),
+		Transport: ProbeTransport(),
+	}
+
+	searches := make([]*batchSearch, len(queries))
+	probes := 0
+	// Пробная проверка сайта в half_open идет для всех имен пакета, а не только для первого
+	admission := circuitAdmission{}
+	for i, query := range queries {
+		plan := planSearchWith(query, opts, admission)
+		// Задачи имени: Telegram, утечки и по одной на каждую проверку сайта
+		searches[i] = &batchSearch{plan: plan, pending: 2 + len(plan.probes)}
+		probes += len(plan.probes)
+	}
+	log.Printf("Starting batch of %d searches, %d site checks", len(queries), probes)
+
+	type completed struct {
+		index  int
+		result SearchResult
+	}
+	completions := make(chan completed, len(queries))
+	jobs := make(chan func())
+	var workers sync.WaitGroup
+	for n := 0; n < cfg.Batch.Concurrency; n++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				job()
+			}
+		}()
+	}
+
+	// Имена идут по очереди, чтобы первые результаты появлялись в потоке как можно раньше
+	go func() {
+		defer close(jobs)
+		for i, search := range searches {
+			i, search := i, search
+			complete := func(update func()) {
+				if search.finish(update) {
+					completions <- completed{i, search.plan.result(search.telegram, search.found, search.breaches)}
+				}
+			}
+			jobs <- func() {
+				telegram := search.plan.lookupTelegram(ctx, bot)
+				complete(func() { search.telegram = telegram })
+			}
+			jobs <- func() {
+				breaches := search.plan.lookupBreaches(ctx)
+				complete(func() { search.breaches = breaches })
+			}
+			for _, p := range search.plan.probes {
+				p := p
+				jobs <- func() {
+					result, found := probeSite(ctx, client, p.site, search.plan.profile, p.account)
+					complete(func() {
+						if found {
+							search.found = append(search.found, result)
+						}
+					})
+				}
+			}
+		}
+	}()
+
+	for range queries {
+		c := <-completions
+		done(c.index, c.result)
+	}
+	workers.Wait()
+}
+
+// batchMatrix строит матрицу найденных сайтов; самые частые сайты первыми
+func batchMatrix(usernames []string, results []SearchResult) BatchMatrix {
+	rows := map[string]*BatchMatrixRow{}
+	for i, result := range results {
+		for _, site := range result.FoundOn {
+			row := rows[site]
+			if row == nil {
+				row = &BatchMatrixRow{Site: site, Hits: make([]bool, len(usernames))}
+				rows[site] = row
+			}
+			row.Hits[i] = true
+			row.Count++
+		}
+	}
+	matrix := BatchMatrix{Usernames: usernames, Sites: make([]BatchMatrixRow, 0, len(rows))}
+	for _, row := range rows {
+		matrix.Sites = append(matrix.Sites, *row)
+	}
+	sort.Slice(matrix.Sites, func(i, j int) bool {
+		if matrix.Sites[i].Count != matrix.Sites[j].Count {
+			return matrix.Sites[i].Count > matrix.Sites[j].Count
+		}
+		return matrix.Sites[i].Site < matrix.Sites[j].Site
+	})
+	return matrix
+}
@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Имена источников в Breach.Sources и SearchResult.BreachErrors
+const (
+	breachSourceHIBP  = "hibp"
+	breachSourceLocal = "local"
+	breachSourceRange = "range"
+)
+
+// maxBreachResponseSize — ограничение на ответ HIBP-совместимого API
+const maxBreachResponseSize = 4 << 20
+
+// Breach — утечка, в которой встречается аккаунт
+type Breach struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title,omitempty"`
+	Domain      string   `json:"domain,omitempty"`
+	BreachDate  string   `json:"breach_date,omitempty"` // YYYY-MM-DD
+	DataClasses []string `json:"data_classes,omitempty"`
+	Sources     []string `json:"sources,omitempty"` // какие источники сообщили об утечке
+}
+
+// BreachProvider — источник данных об утечках. Аккаунт — имя пользователя или email;
+// отсутствие аккаунта в источнике — пустой список без ошибки.
+type BreachProvider interface {
+	Name() string
+	Lookup(ctx context.Context, account string) ([]Breach, error)
+}
+
+// --- HIBP-совместимый API (v3, /breachedaccount) ---
+
+// hibpProvider — клиент haveibeenpwned.com или совместимого сервиса
+type hibpProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func newHIBPProvider(baseURL, apiKey string, timeout time.Duration) *hibpProvider {
+	return &hibpProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *hibpProvider) Name() string { return breachSourceHIBP }
+
+// hibpBreach — элемент ответа /breachedaccount с truncateResponse=false
+type hibpBreach struct {
+	Name        string   `json:"Name"`
+	Title       string   `json:"Title"`
+	Domain      string   `json:"Domain"`
+	BreachDate  string   `json:"BreachDate"`
+	DataClasses []string `json:"DataClasses"`
+}
+
+func (p *hibpProvider) Lookup(ctx context.Context, account string) ([]Breach, error) {
+	target := p.baseURL + "/breachedaccount/" + url.PathEscape(account) + "?truncateResponse=false"
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	// HIBP отклоняет запросы без User-Agent
+	req.Header.Set("User-Agent", "gosearch-tg-app")
+	if p.apiKey != "" {
+		req.Header.Set("hibp-api-key", p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		// net/http пишет в ошибку полный URL, а в нем адрес; ошибка уходит в лог
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return nil, fmt.Errorf("breachedaccount: %w", urlErr.Err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil // аккаунт не найден ни в одной утечке
+	default:
+		return nil, fmt.Errorf("breachedaccount: HTTP %d", resp.StatusCode)
+	}
+
+	var list []hibpBreach
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBreachResponseSize)).Decode(&list); err != nil {
+		return nil, fmt.Errorf("breachedaccount: %w", err)
+	}
+	breaches := make([]Breach, 0, len(list))
+	for _, b := range list {
+		breaches = append(breaches, Breach{
+			Name:        b.Name,
+			Title:       b.Title,
+			Domain:      b.Domain,
+			BreachDate:  b.BreachDate,
+			DataClasses: b.DataClasses,
+		})
+	}
+	return breaches, nil
+}
+
+// --- Собственный индекс (см. breach_index.go) ---
+
+// localBreachProvider — утечки из индекса breach.local_index
+type localBreachProvider struct{}
+
+func (localBreachProvider) Name() string { return breachSourceLocal }
+
+func (localBreachProvider) Lookup(ctx context.Context, account string) ([]Breach, error) {
+	index, err := localBreachIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.Lookup(account)
+}
+
+// breachRangeProvider — индекс на другом инстансе через /breaches/range:
+// туда уходят только первые символы SHA-1 аккаунта, остаток сверяется здесь
+type breachRangeProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func newBreachRangeProvider(baseURL, apiKey string, timeout time.Duration) *breachRangeProvider {
+	return &breachRangeProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *breachRangeProvider) Name() string { return breachSourceRange }
+
+func (p *breachRangeProvider) Lookup(ctx context.Context, account string) ([]Breach, error) {
+	hash := breachAccountHash(account)
+	full := strings.ToUpper(hex.EncodeToString(hash[:]))
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/breaches/range/"+full[:BreachRangePrefixSize], nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("breaches/range: HTTP %d", resp.StatusCode)
+	}
+	var result BreachRange
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBreachResponseSize)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("breaches/range: %w", err)
+	}
+
+	catalogue := map[string]Breach{}
+	for _, breach := range result.Breaches {
+		catalogue[breach.Name] = breach
+	}
+	var breaches []Breach
+	for _, match := range result.Matches {
+		if !strings.EqualFold(match.Suffix, full[BreachRangePrefixSize:]) {
+			continue
+		}
+		for _, name := range match.Breaches {
+			breach := catalogue[name]
+			breach.Name = name
+			breaches = append(breaches, breach)
+		}
+	}
+	return breaches, nil
+}
+
+// normalizeBreachAccount — аккаунты в утечках сравниваются без регистра, пробелов по краям и "@" перед именем
+func normalizeBreachAccount(account string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(account)), "@")
+}
+
+// --- Опрос источников ---
+
+var (
+	breachProvidersOnce sync.Once
+	breachProviderList  []BreachProvider
+)
+
+// breachProviders — источники из конфигурации; ключи берутся из HIBP_API_KEY и BREACH_RANGE_API_KEY
+func breachProviders() []BreachProvider {
+	breachProvidersOnce.Do(func() {
+		cfg := Settings().Breach
+		if cfg.HIBPURL != "" {
+			breachProviderList = append(breachProviderList, newHIBPProvider(cfg.HIBPURL, os.Getenv("HIBP_API_KEY"), time.Duration(cfg.Timeout)))
+		}
+		if cfg.LocalIndex != "" {
+			breachProviderList = append(breachProviderList, localBreachProvider{})
+		}
+		if cfg.RangeURL != "" {
+			breachProviderList = append(breachProviderList, newBreachRangeProvider(cfg.RangeURL, os.Getenv("BREACH_RANGE_API_KEY"), time.Duration(cfg.Timeout)))
+		}
+	})
+	return breachProviderList
+}
+
+// lookupBreaches опрашивает источники параллельно и сливает одинаковые утечки,
+// сохраняя, кто о них сообщил. Ошибки источников не прерывают поиск и возвращаются отдельно.
+func lookupBreaches(ctx context.Context, providers []BreachProvider, account string) ([]Breach, map[string]string) {
+	type answer struct {
+		source   string
+		breaches []Breach
+		err      error
+	}
+	answers := make(chan answer, len(providers))
+	for _, provider := range providers {
+		go func(provider BreachProvider) {
+			breaches, err := provider.Lookup(ctx, account)
+			answers <- answer{provider.Name(), breaches, err}
+		}(provider)
+	}
+
+	merged := map[string]*Breach{}
+	var failures map[string]string
+	for range providers {
+		a := <-answers
+		if a.err != nil {
+			log.Printf("Breach lookup via %s failed: %v", a.source, a.err)
+			if failures == nil {
+				failures = map[string]string{}
+			}
+			failures[a.source] = a.err.Error()
+			continue
+		}
+		for _, breach := range a.breaches {
+			key := strings.ToLower(breach.Name)
+			existing := merged[key]
+			if existing == nil {
+				existing = &Breach{Name: breach.Name}
+				merged[key] = existing
+			}
+			mergeBreach(existing, breach)
+			if !containsString(existing.Sources, a.source) {
+				existing.Sources = append(existing.Sources, a.source)
+			}
+		}
+	}
+
+	breaches := make([]Breach, 0, len(merged))
+	for _, breach := range merged {
+		sort.Strings(breach.Sources)
+		breaches = append(breaches, *breach)
+	}
+	// Сначала свежие утечки
+	sort.Slice(breaches, func(i, j int) bool {
+		if breaches[i].BreachDate != breaches[j].BreachDate {
+			return breaches[i].BreachDate > breaches[j].BreachDate
+		}
+		return breaches[i].Name < breaches[j].Name
+	})
+	return breaches, failures
+}
+
+// mergeBreach дополняет пустые поля и объединяет типы данных
+func mergeBreach(dst *Breach, src Breach) {
+	if dst.Title == "" {
+		dst.Title = src.Title
+	}
+	if dst.Domain == "" {
+		dst.Domain = src.Domain
+	}
+	if dst.BreachDate == "" {
+		dst.BreachDate = src.BreachDate
+	}
+	for _, class := range src.DataClasses {
+		if !containsString(dst.DataClasses, class) {
+			dst.DataClasses = append(dst.DataClasses, class)
+		}
+	}
+}
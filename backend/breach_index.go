@@ -0,0 +1,436 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Формат индекса утечек: магическая строка, длина каталога (uint32), каталог в JSON
+// и отсортированные записи [20 байт SHA-1 аккаунта][4 байта номер утечки в каталоге].
+// Сам аккаунт в индексе не хранится.
+const (
+	breachIndexMagic      = "GSBRIDX1"
+	breachRecordSize      = sha1.Size + 4
+	BreachRangePrefixSize = 5 // символов hex в запросе диапазона, как в Pwned Passwords
+)
+
+// BreachRecord — строка исходных данных: аккаунт и утечка, в которой он встречается
+type BreachRecord struct {
+	Account     string   `json:"account"`
+	Breach      string   `json:"breach"`
+	Title       string   `json:"title,omitempty"`
+	Domain      string   `json:"domain,omitempty"`
+	BreachDate  string   `json:"breach_date,omitempty"`
+	DataClasses []string `json:"data_classes,omitempty"`
+}
+
+// breachCatalogue — заголовок индекса
+type breachCatalogue struct {
+	CreatedAt time.Time `json:"created_at"`
+	Accounts  int       `json:"accounts"`
+	Breaches  []Breach  `json:"breaches"` // номер записи — позиция в списке
+}
+
+// breachAccountHash — SHA-1 нормализованного аккаунта
+func breachAccountHash(account string) [sha1.Size]byte {
+	return sha1.Sum([]byte(normalizeBreachAccount(account)))
+}
+
+// --- Сборка индекса ---
+
+// BreachIndexBuilder собирает индекс из CSV и JSON; в памяти держит только хэши
+type BreachIndexBuilder struct {
+	breaches map[string]*Breach
+	ids      map[string]uint32
+	records  map[[breachRecordSize]byte]bool
+	Skipped  int // записи без аккаунта или утечки
+}
+
+func NewBreachIndexBuilder() *BreachIndexBuilder {
+	return &BreachIndexBuilder{
+		breaches: map[string]*Breach{},
+		ids:      map[string]uint32{},
+		records:  map[[breachRecordSize]byte]bool{},
+	}
+}
+
+// Add добавляет запись; метаданные утечки дополняются из всех записей с ее именем
+func (b *BreachIndexBuilder) Add(rec BreachRecord) {
+	name := strings.TrimSpace(rec.Breach)
+	if normalizeBreachAccount(rec.Account) == "" || name == "" {
+		b.Skipped++
+		return
+	}
+	breach := b.breaches[name]
+	if breach == nil {
+		breach = &Breach{Name: name}
+		b.breaches[name] = breach
+		b.ids[name] = uint32(len(b.ids))
+	}
+	mergeBreach(breach, Breach{Title: rec.Title, Domain: rec.Domain, BreachDate: rec.BreachDate, DataClasses: rec.DataClasses})
+
+	var key [breachRecordSize]byte
+	hash := breachAccountHash(rec.Account)
+	copy(key[:], hash[:])
+	binary.BigEndian.PutUint32(key[sha1.Size:], b.ids[name])
+	b.records[key] = true
+}
+
+// ReadCSV читает CSV с заголовком: account и breach обязательны;
+// title, domain, breach_date и data_classes (через ";") — по желанию
+func (b *BreachIndexBuilder) ReadCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("csv header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["account"]; !ok {
+		return errors.New("csv header: account column is required")
+	}
+	if _, ok := columns["breach"]; !ok {
+		return errors.New("csv header: breach column is required")
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rec := BreachRecord{
+			Account:    field(row, "account"),
+			Breach:     field(row, "breach"),
+			Title:      field(row, "title"),
+			Domain:     field(row, "domain"),
+			BreachDate: field(row, "breach_date"),
+		}
+		for _, class := range strings.Split(field(row, "data_classes"), ";") {
+			if class = strings.TrimSpace(class); class != "" {
+				rec.DataClasses = append(rec.DataClasses, class)
+			}
+		}
+		b.Add(rec)
+	}
+}
+
+// ReadJSON читает массив BreachRecord или по одной записи на строку (NDJSON)
+func (b *BreachIndexBuilder) ReadJSON(r io.Reader) error {
+	buffered := bufio.NewReader(r)
+	first, err := peekNonSpace(buffered)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(buffered)
+	if first == '[' {
+		var list []BreachRecord
+		if err := dec.Decode(&list); err != nil {
+			return err
+		}
+		for _, rec := range list {
+			b.Add(rec)
+		}
+		return nil
+	}
+	for {
+		var rec BreachRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		b.Add(rec)
+	}
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return c, r.UnreadByte()
+		}
+	}
+}
+
+// BreachIndexStats — что попало в индекс
+type BreachIndexStats struct {
+	Records  int `json:"records"`
+	Accounts int `json:"accounts"`
+	Breaches int `json:"breaches"`
+	Skipped  int `json:"skipped"`
+}
+
+// Write записывает индекс; записи отсортированы, чтобы искать диапазоны двоичным поиском
+func (b *BreachIndexBuilder) Write(w io.Writer) (BreachIndexStats, error) {
+	records := make([][breachRecordSize]byte, 0, len(b.records))
+	accounts := map[[sha1.Size]byte]bool{}
+	for key := range b.records {
+		records = append(records, key)
+		accounts[[sha1.Size]byte(key[:sha1.Size])] = true
+	}
+	sort.Slice(records, func(i, j int) bool { return bytes.Compare(records[i][:], records[j][:]) < 0 })
+
+	catalogue := breachCatalogue{
+		CreatedAt: time.Now().UTC(),
+		Accounts:  len(accounts),
+		Breaches:  make([]Breach, len(b.ids)),
+	}
+	for name, id := range b.ids {
+		catalogue.Breaches[id] = *b.breaches[name]
+	}
+	header, err := json.Marshal(catalogue)
+	if err != nil {
+		return BreachIndexStats{}, err
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(breachIndexMagic)
+	binary.Write(bw, binary.BigEndian, uint32(len(header)))
+	bw.Write(header)
+	for _, key := range records {
+		bw.Write(key[:])
+	}
+	stats := BreachIndexStats{Records: len(records), Accounts: len(accounts), Breaches: len(b.ids), Skipped: b.Skipped}
+	return stats, bw.Flush()
+}
+
+// --- Чтение индекса ---
+
+// BreachIndex — открытый индекс; записи читаются с диска по мере поиска
+type BreachIndex struct {
+	file      *os.File
+	catalogue breachCatalogue
+	offset    int64 // начало записей
+	count     int   // число записей
+}
+
+func OpenBreachIndex(path string) (*BreachIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	index, err := readBreachIndexHeader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return index, nil
+}
+
+func readBreachIndexHeader(file *os.File) (*BreachIndex, error) {
+	head := make([]byte, len(breachIndexMagic)+4)
+	if _, err := io.ReadFull(file, head); err != nil {
+		return nil, fmt.Errorf("not a breach index: %w", err)
+	}
+	if string(head[:len(breachIndexMagic)]) != breachIndexMagic {
+		return nil, errors.New("not a breach index")
+	}
+	size := binary.BigEndian.Uint32(head[len(breachIndexMagic):])
+	header := make([]byte, size)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, fmt.Errorf("catalogue: %w", err)
+	}
+	index := &BreachIndex{file: file, offset: int64(len(head)) + int64(size)}
+	if err := json.Unmarshal(header, &index.catalogue); err != nil {
+		return nil, fmt.Errorf("catalogue: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	body := info.Size() - index.offset
+	if body%breachRecordSize != 0 {
+		return nil, errors.New("truncated records")
+	}
+	index.count = int(body / breachRecordSize)
+	return index, nil
+}
+
+func (ix *BreachIndex) Close() error {
+	return ix.file.Close()
+}
+
+func (ix *BreachIndex) record(i int) ([breachRecordSize]byte, error) {
+	var key [breachRecordSize]byte
+	_, err := ix.file.ReadAt(key[:], ix.offset+int64(i)*breachRecordSize)
+	return key, err
+}
+
+// scan перебирает записи, у которых хэш начинается с prefix, начиная с первой такой записи
+func (ix *BreachIndex) scan(prefix []byte, visit func(hash [sha1.Size]byte, breach Breach)) error {
+	var readErr error
+	first := sort.Search(ix.count, func(i int) bool {
+		key, err := ix.record(i)
+		if err != nil {
+			readErr = err
+			return true
+		}
+		return bytes.Compare(key[:len(prefix)], prefix) >= 0
+	})
+	if readErr != nil {
+		return readErr
+	}
+	for i := first; i < ix.count; i++ {
+		key, err := ix.record(i)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(key[:], prefix) {
+			break
+		}
+		id := binary.BigEndian.Uint32(key[sha1.Size:])
+		if int(id) >= len(ix.catalogue.Breaches) {
+			return fmt.Errorf("record %d: unknown breach %d", i, id)
+		}
+		visit([sha1.Size]byte(key[:sha1.Size]), ix.catalogue.Breaches[id])
+	}
+	return nil
+}
+
+// Lookup — утечки с этим аккаунтом
+func (ix *BreachIndex) Lookup(account string) ([]Breach, error) {
+	hash := breachAccountHash(account)
+	var breaches []Breach
+	err := ix.scan(hash[:], func(_ [sha1.Size]byte, breach Breach) {
+		breaches = append(breaches, breach)
+	})
+	return breaches, err
+}
+
+// BreachRangeMatch — аккаунт из диапазона: остаток хэша и его утечки
+type BreachRangeMatch struct {
+	Suffix   string   `json:"suffix"`
+	Breaches []string `json:"breaches"`
+}
+
+// BreachRange — ответ на запрос диапазона: все аккаунты с хэшем на prefix
+// и описания утечек, которые в них встречаются
+type BreachRange struct {
+	Prefix   string             `json:"prefix"`
+	Matches  []BreachRangeMatch `json:"matches"`
+	Breaches []Breach           `json:"breaches"`
+}
+
+// Range возвращает все хэши, начинающиеся с prefix (5 символов hex)
+func (ix *BreachIndex) Range(prefix string) (*BreachRange, error) {
+	prefix = strings.ToUpper(prefix)
+	if !validBreachRangePrefix(prefix) {
+		return nil, fmt.Errorf("prefix must be %d hex characters", BreachRangePrefixSize)
+	}
+	// 5 символов hex — 2,5 байта: ищем по 2 байтам и отсеиваем по строке
+	raw, _ := hex.DecodeString(prefix[:BreachRangePrefixSize-1])
+
+	result := &BreachRange{Prefix: prefix, Matches: []BreachRangeMatch{}, Breaches: []Breach{}}
+	seen := map[string]bool{}
+	err := ix.scan(raw, func(hash [sha1.Size]byte, breach Breach) {
+		full := strings.ToUpper(hex.EncodeToString(hash[:]))
+		if !strings.HasPrefix(full, prefix) {
+			return
+		}
+		suffix := full[BreachRangePrefixSize:]
+		if n := len(result.Matches); n > 0 && result.Matches[n-1].Suffix == suffix {
+			result.Matches[n-1].Breaches = append(result.Matches[n-1].Breaches, breach.Name)
+		} else {
+			result.Matches = append(result.Matches, BreachRangeMatch{Suffix: suffix, Breaches: []string{breach.Name}})
+		}
+		if !seen[breach.Name] {
+			seen[breach.Name] = true
+			result.Breaches = append(result.Breaches, breach)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func validBreachRangePrefix(prefix string) bool {
+	if len(prefix) != BreachRangePrefixSize {
+		return false
+	}
+	for _, c := range prefix {
+		if !strings.ContainsRune("0123456789ABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// --- Диапазонный API ---
+
+var (
+	breachIndexOnce sync.Once
+	breachIndex     *BreachIndex
+	breachIndexErr  error
+)
+
+// localBreachIndex — индекс из breach.local_index; открывается при первом обращении
+func localBreachIndex() (*BreachIndex, error) {
+	breachIndexOnce.Do(func() {
+		path := Settings().Breach.LocalIndex
+		if path == "" {
+			breachIndexErr = errors.New("breach.local_index is not configured")
+			return
+		}
+		breachIndex, breachIndexErr = OpenBreachIndex(path)
+	})
+	return breachIndex, breachIndexErr
+}
+
+// handleBreachRange — GET /breaches/range/{prefix}: k-анонимный поиск по индексу.
+// Клиент отправляет только первые 5 символов SHA-1 аккаунта и сверяет остаток у себя.
+func handleBreachRange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	index, err := localBreachIndex()
+	if err != nil {
+		log.Printf("Breach index unavailable: %v", err)
+		http.Error(w, "Breach index is not available", http.StatusServiceUnavailable)
+		return
+	}
+	prefix := strings.TrimPrefix(r.URL.Path, "/breaches/range/")
+	result, err := index.Range(prefix)
+	if err != nil {
+		http.Error(w, "Bad prefix: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
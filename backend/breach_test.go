@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// staticBreaches — источник с заранее известным ответом
+type staticBreaches struct {
+	name     string
+	breaches []Breach
+	err      error
+}
+
+func (s staticBreaches) Name() string { return s.name }
+
+func (s staticBreaches) Lookup(context.Context, string) ([]Breach, error) {
+	return s.breaches, s.err
+}
+
+// hibpStandIn отвечает как /breachedaccount: status для любого аккаунта, кроме known
+func hibpStandIn(t *testing.T, known string, status int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") == "" || r.Header.Get("hibp-api-key") != "test-key" {
+			t.Errorf("request without User-Agent or API key: %v", r.Header)
+		}
+		if r.URL.Query().Get("truncateResponse") != "false" {
+			t.Errorf("request %s without truncateResponse=false", r.URL)
+		}
+		if r.URL.Path != "/breachedaccount/"+known {
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode([]hibpBreach{
+			{Name: "Adobe", Title: "Adobe", Domain: "adobe.com", BreachDate: "2013-10-04", DataClasses: []string{"Email addresses", "Passwords"}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHIBPProvider(t *testing.T) {
+	server := hibpStandIn(t, "alice@example.com", http.StatusNotFound)
+	provider := newHIBPProvider(server.URL+"/", "test-key", time.Second)
+
+	breaches, err := provider.Lookup(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("200: %v", err)
+	}
+	want := []Breach{{Name: "Adobe", Title: "Adobe", Domain: "adobe.com", BreachDate: "2013-10-04", DataClasses: []string{"Email addresses", "Passwords"}}}
+	if !reflect.DeepEqual(breaches, want) {
+		t.Errorf("200: breaches %+v, want %+v", breaches, want)
+	}
+
+	breaches, err = provider.Lookup(context.Background(), "bob@example.com")
+	if err != nil || len(breaches) != 0 {
+		t.Errorf("404: breaches %+v, err %v; want none without error", breaches, err)
+	}
+}
+
+func TestHIBPFailuresInBreachErrors(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		server := hibpStandIn(t, "", status)
+		t.Setenv("HIBP_API_KEY", "test-key")
+		useBreachConfig(t, BreachConfig{HIBPURL: server.URL, Timeout: Duration(time.Second)})
+		useTestSites(t, statusSite("Status", "https://status.example/{}"))
+
+		plan := planSearch("alice@example.com", SearchOptions{Type: SearchTypeEmail})
+		result := plan.result(nil, nil, plan.lookupBreaches(context.Background()))
+		if got, want := result.BreachErrors[breachSourceHIBP], fmt.Sprintf("breachedaccount: HTTP %d", status); got != want {
+			t.Errorf("HTTP %d: breach_errors %v, want the status under %q", status, result.BreachErrors, breachSourceHIBP)
+		}
+		if len(result.Breaches) != 0 {
+			t.Errorf("HTTP %d: breaches %+v, want none", status, result.Breaches)
+		}
+	}
+}
+
+func TestBreachRangeProvider(t *testing.T) {
+	hash := breachAccountHash("alice@example.com")
+	full := strings.ToUpper(hex.EncodeToString(hash[:]))
+	prefix, suffix := full[:BreachRangePrefixSize], full[BreachRangePrefixSize:]
+	// Другой аккаунт с тем же префиксом
+	other := strings.Repeat("0", len(suffix))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/breaches/range/"+prefix {
+			t.Errorf("request %s, want only the %d-character prefix %s", r.URL.Path, BreachRangePrefixSize, prefix)
+		}
+		if r.Header.Get("Authorization") != "Bearer range-key" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		json.NewEncoder(w).Encode(BreachRange{
+			Prefix: prefix,
+			Matches: []BreachRangeMatch{
+				{Suffix: strings.ToLower(suffix), Breaches: []string{"Adobe", "Dropbox"}},
+				{Suffix: other, Breaches: []string{"LinkedIn"}},
+			},
+			Breaches: []Breach{
+				{Name: "Adobe", Domain: "adobe.com"},
+				{Name: "LinkedIn", Domain: "linkedin.com"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	provider := newBreachRangeProvider(server.URL, "range-key", time.Second)
+	// Аккаунт нормализуется так же, как при сборке индекса
+	breaches, err := provider.Lookup(context.Background(), "  Alice@Example.com ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Breach{{Name: "Adobe", Domain: "adobe.com"}, {Name: "Dropbox"}}
+	if !reflect.DeepEqual(breaches, want) {
+		t.Errorf("breaches %+v, want %+v", breaches, want)
+	}
+}
+
+func TestBreachRangeProviderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, failures := lookupBreaches(context.Background(), []BreachProvider{newBreachRangeProvider(server.URL, "", time.Second)}, "alice")
+	if got := failures[breachSourceRange]; got != "breaches/range: HTTP 401" {
+		t.Errorf("failures %v, want breaches/range: HTTP 401 under %q", failures, breachSourceRange)
+	}
+}
+
+func TestLookupBreachesMerges(t *testing.T) {
+	providers := []BreachProvider{
+		staticBreaches{name: breachSourceHIBP, breaches: []Breach{
+			{Name: "Adobe", Title: "Adobe", BreachDate: "2013-10-04", DataClasses: []string{"Email addresses", "Passwords"}},
+			{Name: "Canva", BreachDate: "2019-05-24"},
+		}},
+		staticBreaches{name: breachSourceLocal, breaches: []Breach{
+			{Name: "adobe", Domain: "adobe.com", DataClasses: []string{"Passwords", "Password hints"}},
+			{Name: "Old", BreachDate: "2008-01-01"},
+		}},
+		staticBreaches{name: breachSourceRange, err: errors.New("breaches/range: HTTP 429")},
+	}
+
+	breaches, failures := lookupBreaches(context.Background(), providers, "alice")
+	var names []string
+	for _, breach := range breaches {
+		names = append(names, strings.ToLower(breach.Name))
+	}
+	// Сначала свежие; одна и та же утечка из двух источников — одна запись
+	if want := []string{"canva", "adobe", "old"}; !equalStrings(names, want) {
+		t.Fatalf("breaches %v, want %v", names, want)
+	}
+
+	// Кто ответил первым, тот задал имя и порядок типов данных; остальное сливается
+	adobe := breaches[1]
+	sort.Strings(adobe.DataClasses)
+	adobe.Name = "Adobe"
+	want := Breach{Name: "Adobe", Title: "Adobe", Domain: "adobe.com", BreachDate: "2013-10-04",
+		DataClasses: []string{"Email addresses", "Password hints", "Passwords"}, Sources: []string{breachSourceHIBP, breachSourceLocal}}
+	if !reflect.DeepEqual(adobe, want) {
+		t.Errorf("merged %+v, want %+v", adobe, want)
+	}
+	if sources := breaches[0].Sources; !equalStrings(sources, []string{breachSourceHIBP}) {
+		t.Errorf("canva sources %v, want only %s", sources, breachSourceHIBP)
+	}
+	if !reflect.DeepEqual(failures, map[string]string{breachSourceRange: "breaches/range: HTTP 429"}) {
+		t.Errorf("failures %v", failures)
+	}
+}
+
+// useBreachConfig подменяет источники утечек до конца теста
+func useBreachConfig(t *testing.T, breach BreachConfig) {
+	t.Helper()
+	cfg := Settings()
+	saved := cfg.Breach
+	cfg.Breach = breach
+	breachProvidersOnce, breachProviderList = sync.Once{}, nil
+	t.Cleanup(func() {
+		cfg.Breach = saved
+		breachProvidersOnce, breachProviderList = sync.Once{}, nil
+	})
+}
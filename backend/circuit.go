@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Состояния цепи сайта
+const (
+	CircuitClosed   = "closed"    // сайт проверяется как обычно
+	CircuitOpen     = "open"      // сайт пропускается до конца cooldown
+	CircuitHalfOpen = "half_open" // идет одна пробная проверка
+)
+
+// skipCircuitOpen — причина пропуска сайта в SearchResult.Skipped
+const skipCircuitOpen = "circuit_open"
+
+// siteCircuit — автомат состояний одного сайта
+type siteCircuit struct {
+	state    string
+	failures int // неудач подряд
+	openedAt time.Time
+	trial    bool // пробная проверка в half_open уже выдана
+}
+
+// circuitBreakers — цепи всех сайтов этого инстанса
+type circuitBreakers struct {
+	mu       sync.Mutex
+	circuits map[string]*siteCircuit
+	now      func() time.Time
+}
+
+var siteCircuits = &circuitBreakers{circuits: map[string]*siteCircuit{}, now: time.Now}
+
+func (b *circuitBreakers) get(site string) *siteCircuit {
+	c := b.circuits[site]
+	if c == nil {
+		c = &siteCircuit{state: CircuitClosed}
+		b.circuits[site] = c
+	}
+	return c
+}
+
+// allow решает, проверять ли сайт сейчас. После cooldown открытая цепь
+// переходит в half_open и пропускает ровно одну пробную проверку.
+func (b *circuitBreakers) allow(site string) bool {
+	cfg := Settings().Circuit
+	if cfg.Failures <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.get(site)
+	switch c.state {
+	case CircuitOpen:
+		if b.now().Sub(c.openedAt) < time.Duration(cfg.Cooldown) {
+			return false
+		}
+		c.state = CircuitHalfOpen
+		c.trial = true
+		return true
+	case CircuitHalfOpen:
+		if c.trial {
+			return false
+		}
+		c.trial = true
+		return true
+	}
+	return true
+}
+
+// circuitAdmission — решения allow на один поиск или пакет: сайт в half_open,
+// пропущенный для первого имени пакета, проверяется и для остальных
+type circuitAdmission map[string]bool
+
+func (a circuitAdmission) allow(site string) bool {
+	allowed, ok := a[site]
+	if !ok {
+		allowed = siteCircuits.allow(site)
+		a[site] = allowed
+	}
+	return allowed
+}
+
+// record учитывает исход проверки: неудача в half_open или failures неудач подряд размыкают цепь.
+// Пропущенная проверка цепь не меняет, но пробную в half_open можно выдать снова.
+func (b *circuitBreakers) record(site string, outcome probeOutcome) {
+	cfg := Settings().Circuit
+	if cfg.Failures <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.get(site)
+	switch {
+	case outcome == probeSkipped:
+		if c.state == CircuitHalfOpen {
+			c.trial = false
+		}
+		return
+	case outcome != probeError:
+		c.state = CircuitClosed
+		c.failures = 0
+		c.trial = false
+		return
+	}
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= cfg.Failures {
+		c.state = CircuitOpen
+		c.openedAt = b.now()
+		c.trial = false
+	}
+}
+
+// reset замыкает цепь вручную
+func (b *circuitBreakers) reset(site string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.circuits, site)
+}
+
+// CircuitStatus — состояние цепи для админки
+type CircuitStatus struct {
+	Site     string     `json:"site"`
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"`
+}
+
+// snapshot — все сайты не в состоянии closed или с неудачами подряд
+func (b *circuitBreakers) snapshot() []CircuitStatus {
+	cooldown := time.Duration(Settings().Circuit.Cooldown)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	list := []CircuitStatus{}
+	for site, c := range b.circuits {
+		if c.state == CircuitClosed && c.failures == 0 {
+			continue
+		}
+		status := CircuitStatus{Site: site, State: c.state, Failures: c.failures}
+		if c.state != CircuitClosed {
+			openedAt, retryAt := c.openedAt.UTC(), c.openedAt.Add(cooldown).UTC()
+			status.OpenedAt, status.RetryAt = &openedAt, &retryAt
+		}
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Site < list[j].Site })
+	return list
+}
+
+// handleAdminCircuits: GET — сайты с неудачами и их состояние, POST ?site=NAME — замкнуть цепь
+func handleAdminCircuits(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"failures": Settings().Circuit.Failures,
+			"cooldown": Settings().Circuit.Cooldown,
+			"circuits": siteCircuits.snapshot(),
+		})
+	case "POST":
+		site := r.URL.Query().Get("site")
+		if site == "" {
+			http.Error(w, "site parameter is required", http.StatusBadRequest)
+			return
+		}
+		siteCircuits.reset(site)
+		json.NewEncoder(w).Encode(map[string]string{"site": site, "state": CircuitClosed})
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// useCircuits включает автомат с порогом failures на время теста
+func useCircuits(t *testing.T, failures int) {
+	t.Helper()
+	cfg := Settings()
+	saved := cfg.Circuit
+	cfg.Circuit.Failures = failures
+	cfg.Circuit.Cooldown = Duration(time.Minute)
+	t.Cleanup(func() { cfg.Circuit = saved })
+}
+
+func circuitState(site string) string {
+	siteCircuits.mu.Lock()
+	defer siteCircuits.mu.Unlock()
+	if c := siteCircuits.circuits[site]; c != nil {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+func siteProbes(site string) int {
+	siteStatsMu.Lock()
+	defer siteStatsMu.Unlock()
+	if stat := siteStats[site]; stat != nil {
+		return stat.probes
+	}
+	return 0
+}
+
+func TestCancelledProbeKeepsCircuitClosed(t *testing.T) {
+	useCircuits(t, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Сайт отвечает дольше, чем живет поиск
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	site := statusSite("CircuitCancelled", server.URL+"/{}")
+	t.Cleanup(func() { siteCircuits.reset(site.Name) })
+	if _, found := probeSite(ctx, server.Client(), site, HeaderProfile{}, "alice"); found {
+		t.Fatal("cancelled probe reported a profile")
+	}
+	if state := circuitState(site.Name); state != CircuitClosed {
+		t.Errorf("circuit state = %s after a cancelled search, want %s", state, CircuitClosed)
+	}
+	if probes := siteProbes(site.Name); probes != 0 {
+		t.Errorf("priority stats count %d probes after a cancelled search, want 0", probes)
+	}
+}
+
+func TestFailedProbeOpensCircuit(t *testing.T) {
+	useCircuits(t, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	baseURL := server.URL
+	server.Close() // соединение отклоняется — это неудача сайта
+
+	site := statusSite("CircuitFailed", baseURL+"/{}")
+	t.Cleanup(func() { siteCircuits.reset(site.Name) })
+	probeSite(context.Background(), http.DefaultClient, site, HeaderProfile{}, "alice")
+	if state := circuitState(site.Name); state != CircuitOpen {
+		t.Errorf("circuit state = %s after a failed probe, want %s", state, CircuitOpen)
+	}
+}
+
+func TestCancelledTrialIsGivenAgain(t *testing.T) {
+	useCircuits(t, 1)
+	const site = "CircuitTrial"
+	t.Cleanup(func() { siteCircuits.reset(site) })
+
+	siteCircuits.record(site, probeError)
+	siteCircuits.mu.Lock()
+	siteCircuits.circuits[site].openedAt = time.Now().Add(-2 * time.Minute)
+	siteCircuits.mu.Unlock()
+
+	if !siteCircuits.allow(site) {
+		t.Fatal("cooldown is over, but the trial probe was not allowed")
+	}
+	if siteCircuits.allow(site) {
+		t.Fatal("second probe allowed while the trial is running")
+	}
+	siteCircuits.record(site, probeSkipped)
+	if !siteCircuits.allow(site) {
+		t.Error("trial was cancelled, but no new trial is allowed")
+	}
+	if state := circuitState(site); state != CircuitHalfOpen {
+		t.Errorf("circuit state = %s, want %s", state, CircuitHalfOpen)
+	}
+}
+
+func TestBatchSharesCircuitTrial(t *testing.T) {
+	useCircuits(t, 1)
+	useTestSites(t, statusSite("HalfOpen", "https://half-open.example/{}"))
+	t.Cleanup(func() { siteCircuits.reset("HalfOpen") })
+	halfOpen := func() {
+		siteCircuits.reset("HalfOpen")
+		siteCircuits.record("HalfOpen", probeError)
+		siteCircuits.mu.Lock()
+		siteCircuits.circuits["HalfOpen"].openedAt = time.Now().Add(-2 * time.Minute)
+		siteCircuits.mu.Unlock()
+	}
+
+	// Пакет: пробную проверку получают все имена
+	halfOpen()
+	admission := circuitAdmission{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if plan := planSearchWith(name, SearchOptions{}, admission); len(plan.probes) != 1 {
+			t.Errorf("batch name %s: %d probes, skipped %v; want the trial probe", name, len(plan.probes), plan.skipped)
+		}
+	}
+
+	// Отдельные поиски: пробная проверка одна
+	halfOpen()
+	if plan := planSearch("alice", SearchOptions{}); len(plan.probes) != 1 {
+		t.Errorf("first search: %d probes, want the trial probe", len(plan.probes))
+	}
+	if plan := planSearch("bob", SearchOptions{}); plan.skipped["HalfOpen"] != skipCircuitOpen {
+		t.Errorf("second search: skipped %v, want HalfOpen as %s", plan.skipped, skipCircuitOpen)
+	}
+}
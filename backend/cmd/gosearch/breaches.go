@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	handler "gosearch-tg-backend"
+)
+
+// breachesCommand — команды для работы с индексом утечек
+func breachesCommand(args []string) error {
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "index":
+		return breachesIndex(args[1:])
+	case "lookup":
+		return breachesLookup(args[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	return nil
+}
+
+func breachesIndex(args []string) error {
+	fs := flag.NewFlagSet("breaches index", flag.ExitOnError)
+	output := fs.String("o", "", "index file to write")
+	format := fs.String("format", "", "input format: csv, json (default by file extension)")
+	fs.Parse(args)
+	if *output == "" || fs.NArg() == 0 {
+		return errors.New("breaches index: expected -o FILE and at least one input FILE")
+	}
+
+	builder := handler.NewBreachIndexBuilder()
+	for _, path := range fs.Args() {
+		if err := readBreachFile(builder, path, *format); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	// Пишем во временный файл рядом, чтобы работающий сервер не увидел недописанный индекс
+	tmp, err := os.CreateTemp(filepath.Dir(*output), ".breaches-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	stats, err := builder.Write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), *output); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d records, %d accounts, %d breaches, %d skipped\n", stats.Records, stats.Accounts, stats.Breaches, stats.Skipped)
+	return nil
+}
+
+func readBreachFile(builder *handler.BreachIndexBuilder, path, format string) error {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if format == "ndjson" || format == "jsonl" {
+			format = "json"
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	switch format {
+	case "csv":
+		return builder.ReadCSV(file)
+	case "json":
+		return builder.ReadJSON(file)
+	}
+	return fmt.Errorf("unknown format %q: expected csv or json", format)
+}
+
+// breachesLookup — проверить индекс на одном аккаунте без запуска сервера
+func breachesLookup(args []string) error {
+	fs := flag.NewFlagSet("breaches lookup", flag.ExitOnError)
+	path := fs.String("index", handler.Settings().Breach.LocalIndex, "index file (default breach.local_index)")
+	fs.Parse(args)
+	if *path == "" || fs.NArg() != 1 {
+		return errors.New("breaches lookup: expected -index FILE and one ACCOUNT")
+	}
+
+	index, err := handler.OpenBreachIndex(*path)
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+	breaches, err := index.Lookup(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	for _, breach := range breaches {
+		fmt.Printf("%s\t%s\t%s\n", breach.Name, breach.BreachDate, strings.Join(breach.DataClasses, ", "))
+	}
+	fmt.Fprintf(os.Stderr, "%d breaches\n", len(breaches))
+	return nil
+}
@@ -0,0 +1,189 @@
+// Command gosearch запускает бэкенд вне Vercel: HTTP-сервер или бота в режиме long polling.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	handler "gosearch-tg-backend"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  gosearch [serve] [-addr :8080]   HTTP API (то же, что на Vercel)
+  gosearch poll [-timeout 30s]     Telegram-бот через getUpdates
+  gosearch config                  проверить и вывести действующую конфигурацию
+  gosearch sites lint [-json] [FILE]
+                                   проверить базу сайтов (по умолчанию sites.source)
+  gosearch sites import -format sherlock|whatsmyname|maigret [-merge] FILE
+                                   перевести правила другого инструмента в наш формат
+  gosearch sites export -format whatsmyname|sherlock [-verify] [FILE]
+                                   выгрузить базу в формат другого инструмента
+  gosearch sites selftest [-format markdown|json] [-fixtures DIR | -record DIR] [-sites A,B] [FILE]
+                                   проверить правила на known_present/known_absent
+  gosearch breaches index -o FILE [-format csv|json] FILE...
+                                   собрать индекс утечек для breach.local_index
+  gosearch breaches lookup [-index FILE] ACCOUNT
+                                   найти аккаунт в индексе
+  gosearch openapi [check]         вывести описание /v1 или сверить его с типами в коде
+  gosearch apikey -id ID -scopes search[,admin,export,breaches] [-owner TEAM] [-tier TIER]
+                                   новый API-ключ и запись для API_KEYS`)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		err = serve(ctx, args)
+	case "poll":
+		err = poll(ctx, args)
+	case "apikey":
+		err = apikey(args)
+	case "config":
+		err = printConfig()
+	case "sites":
+		err = sitesCommand(ctx, args)
+	case "breaches":
+		err = breachesCommand(args)
+	case "openapi":
+		err = openapiCommand(args)
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func serve(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	defaultAddr := ":8080"
+	if port := os.Getenv("PORT"); port != "" {
+		defaultAddr = ":" + port
+	}
+	addr := fs.String("addr", defaultAddr, "listen address")
+	fs.Parse(args)
+	handler.Settings()
+	watchSites(ctx)
+
+	server := &http.Server{Addr: *addr, Handler: http.HandlerFunc(handler.Handler)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Listening on %s", *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// watchSites перезагружает базу сайтов по SIGHUP и при изменении файла
+func watchSites(ctx context.Context) {
+	if _, err := handler.ReloadSites(ctx); err != nil {
+		log.Fatalf("Error loading sites data: %v", err)
+	}
+	go handler.WatchSiteSource(ctx)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				signal.Stop(hup)
+				return
+			case <-hup:
+				if _, err := handler.ReloadSites(ctx); err != nil {
+					log.Printf("Sites reload on SIGHUP rejected: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func poll(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("poll", flag.ExitOnError)
+	apiURL := fs.String("api-url", os.Getenv("TELEGRAM_API_URL"), "Bot API base URL")
+	timeout := fs.Duration("timeout", 30*time.Second, "long polling timeout")
+	fs.Parse(args)
+	handler.Settings()
+	watchSites(ctx)
+
+	poller := &handler.Poller{
+		Token:   os.Getenv("TELEGRAM_BOT_TOKEN"),
+		APIURL:  *apiURL,
+		Timeout: *timeout,
+	}
+	return poller.Run(ctx)
+}
+
+func apikey(args []string) error {
+	fs := flag.NewFlagSet("apikey", flag.ExitOnError)
+	id := fs.String("id", "", "key id (shown in usage reports)")
+	scopes := fs.String("scopes", handler.ScopeSearch, "comma-separated scopes: search, admin, export, breaches")
+	owner := fs.String("owner", "", "team the usage is billed to")
+	tier := fs.String("tier", "", "quota tier from QUOTA_TIERS")
+	fs.Parse(args)
+	if *id == "" {
+		return errors.New("apikey: -id is required")
+	}
+
+	key, err := handler.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+	record := handler.APIKey{
+		ID:     *id,
+		Hash:   handler.HashAPIKey(key),
+		Scopes: strings.Split(*scopes, ","),
+		Owner:  *owner,
+		Tier:   *tier,
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	// Ключ показываем один раз: на сервере хранится только хэш
+	fmt.Fprintf(os.Stderr, "API key (save it now, it is not stored): %s\n", key)
+	fmt.Println(string(recordJSON))
+	return nil
+}
+
+func printConfig() error {
+	cfg, err := handler.LoadConfig()
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
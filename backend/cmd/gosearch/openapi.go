@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	handler "gosearch-tg-backend"
+)
+
+// openapiCommand печатает описание /v1 или сверяет его с Go-типами
+func openapiCommand(args []string) error {
+	if len(args) == 0 {
+		_, err := os.Stdout.Write(handler.OpenAPISpec())
+		return err
+	}
+	if args[0] != "check" {
+		usage()
+		os.Exit(2)
+	}
+
+	problems, err := handler.CheckOpenAPI()
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Printf("openapi.json: %s\n", problem)
+	}
+	fmt.Fprintf(os.Stderr, "%d problems\n", len(problems))
+	if len(problems) > 0 {
+		return errors.New("openapi check: openapi.json is out of sync with the code")
+	}
+	return nil
+}
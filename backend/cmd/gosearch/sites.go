@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	handler "gosearch-tg-backend"
+)
+
+// sitesCommand — команды для работы с базой сайтов
+func sitesCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "lint":
+		return sitesLint(ctx, args[1:])
+	case "import":
+		return sitesImport(ctx, args[1:])
+	case "export":
+		return sitesExport(ctx, args[1:])
+	case "selftest":
+		return sitesSelfTest(ctx, args[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	return nil
+}
+
+// loadSitesArg читает базу из аргумента FILE, иначе из sites.source
+func loadSitesArg(ctx context.Context, fs *flag.FlagSet) (string, []handler.SiteInfo, error) {
+	source := fs.Arg(0)
+	if source == "" {
+		source = handler.Settings().Sites.Source
+	}
+	list, err := handler.LoadSiteDatabase(ctx, source)
+	if source == "" {
+		source = "embedded data.json"
+	}
+	return source, list, err
+}
+
+func sitesLint(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sites lint", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print issues as JSON")
+	fs.Parse(args)
+
+	source, list, err := loadSitesArg(ctx, fs)
+	if err != nil {
+		return err
+	}
+
+	issues := handler.LintSites(list)
+	errorCount := 0
+	for _, issue := range issues {
+		if issue.Severity == handler.SeverityError {
+			errorCount++
+		}
+	}
+
+	if *asJSON {
+		if issues == nil {
+			issues = []handler.SiteIssue{}
+		}
+		out, err := json.MarshalIndent(issues, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, issue := range issues {
+			fmt.Printf("%s: %s\n", source, issue)
+		}
+		fmt.Fprintf(os.Stderr, "%d sites, %d errors, %d warnings\n", len(list), errorCount, len(issues)-errorCount)
+	}
+
+	if errorCount > 0 {
+		return errors.New("sites lint: database has errors")
+	}
+	return nil
+}
+
+func sitesImport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sites import", flag.ExitOnError)
+	format := fs.String("format", handler.FormatSherlock, "source format: sherlock, whatsmyname, maigret")
+	merge := fs.Bool("merge", false, "print the whole database with imported sites appended")
+	against := fs.String("sites", "", "database to dedupe against (default sites.source)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("sites import: expected exactly one input FILE")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	source := *against
+	if source == "" {
+		source = handler.Settings().Sites.Source
+	}
+	existing, err := handler.LoadSiteDatabase(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	result, err := handler.ImportSites(*format, data, existing)
+	if err != nil {
+		return err
+	}
+	for _, note := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "skipped: %s: %s\n", note.Site, note.Message)
+	}
+	for _, note := range result.Lossy {
+		fmt.Fprintf(os.Stderr, "lossy: %s: %s\n", note.Site, note.Message)
+	}
+	fmt.Fprintf(os.Stderr, "%d imported, %d skipped, %d lossy notes\n", len(result.Imported), len(result.Skipped), len(result.Lossy))
+
+	var out interface{} = result.Imported
+	if *merge {
+		out = map[string]interface{}{"websites": append(existing, result.Imported...)}
+	}
+	encoded, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(encoded))
+	return nil
+}
+
+func sitesExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sites export", flag.ExitOnError)
+	format := fs.String("format", handler.FormatWhatsMyName, "target format: whatsmyname, sherlock")
+	verify := fs.Bool("verify", false, "re-import the result and report rules that did not survive the round trip")
+	fs.Parse(args)
+
+	_, list, err := loadSitesArg(ctx, fs)
+	if err != nil {
+		return err
+	}
+	result, err := handler.ExportSites(*format, list)
+	if err != nil {
+		return err
+	}
+	for _, note := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "skipped: %s: %s\n", note.Site, note.Message)
+	}
+	for _, note := range result.Lossy {
+		fmt.Fprintf(os.Stderr, "lossy: %s: %s\n", note.Site, note.Message)
+	}
+	fmt.Fprintf(os.Stderr, "%d exported, %d skipped, %d lossy notes\n", result.Exported, len(result.Skipped), len(result.Lossy))
+
+	if *verify {
+		diffs, err := handler.VerifyExport(*format, list, result)
+		if err != nil {
+			return err
+		}
+		for _, note := range diffs {
+			fmt.Fprintf(os.Stderr, "round trip: %s: %s\n", note.Site, note.Message)
+		}
+		if len(diffs) > 0 {
+			return fmt.Errorf("sites export: %d sites changed after round trip", len(diffs))
+		}
+	}
+	fmt.Println(string(result.Data))
+	return nil
+}
+
+func sitesSelfTest(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sites selftest", flag.ExitOnError)
+	format := fs.String("format", "markdown", "report format: markdown, json")
+	fixtures := fs.String("fixtures", "", "replay recorded responses from DIR instead of the network")
+	record := fs.String("record", "", "record live responses into DIR for later -fixtures runs")
+	only := fs.String("sites", "", "comma-separated site names to test (default all)")
+	concurrency := fs.Int("concurrency", 10, "parallel sites")
+	fs.Parse(args)
+	if *fixtures != "" && *record != "" {
+		return errors.New("sites selftest: -fixtures and -record are mutually exclusive")
+	}
+
+	_, list, err := loadSitesArg(ctx, fs)
+	if err != nil {
+		return err
+	}
+	if *only != "" {
+		wanted := map[string]bool{}
+		for _, name := range strings.Split(*only, ",") {
+			wanted[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+		var selected []handler.SiteInfo
+		for _, site := range list {
+			if wanted[strings.ToLower(site.Name)] {
+				selected = append(selected, site)
+			}
+		}
+		list = selected
+	}
+
+	client := &http.Client{
+		Timeout:   time.Duration(handler.Settings().Search.ClientTimeout),
+		Transport: handler.ProbeTransport(),
+	}
+	switch {
+	case *fixtures != "":
+		client.Transport = handler.FixtureTransport(*fixtures)
+	case *record != "":
+		client.Transport = handler.RecordingTransport(*record, handler.ProbeTransport())
+	}
+
+	report := handler.SelfTestSites(ctx, client, list, *concurrency)
+	switch *format {
+	case "json":
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	case "markdown":
+		fmt.Print(report.Markdown())
+	default:
+		return fmt.Errorf("sites selftest: unknown format %q", *format)
+	}
+
+	if report.Failed() {
+		return errors.New("sites selftest: some rules give wrong answers")
+	}
+	return nil
+}
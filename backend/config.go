@@ -0,0 +1,528 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config — настройки бэкенда. Порядок: значения по умолчанию, затем файл из
+// GOSEARCH_CONFIG (JSON или YAML), затем переменные окружения.
+// Секреты (токен бота, секрет вебхука, API-ключи) читаются только из окружения
+// и в Config не попадают.
+type Config struct {
+	Sites    SitesConfig    `json:"sites"`
+	Search   SearchConfig   `json:"search"`
+	Telegram TelegramConfig `json:"telegram"`
+	CORS     CORSConfig     `json:"cors"`
+	Quota    QuotaConfig    `json:"quota"`
+	Circuit  CircuitConfig  `json:"circuit"`
+	Proxy    ProxyConfig    `json:"proxy"`
+	Breach   BreachConfig   `json:"breach"`
+	Batch    BatchConfig    `json:"batch"`
+}
+
+// SitesConfig — откуда брать базу сайтов
+type SitesConfig struct {
+	Source        string   `json:"source"`         // путь к файлу или https-URL; пусто — встроенная data.json
+	WatchInterval Duration `json:"watch_interval"` // как часто проверять изменения файла; 0 — не следить
+}
+
+// SearchConfig — бюджет и «личность» проверок сайтов
+type SearchConfig struct {
+	ClientTimeout Duration `json:"client_timeout"` // таймаут HTTP-клиента на один запрос
+	SiteTimeout   Duration `json:"site_timeout"`   // общий дедлайн на проверку всех сайтов
+	ProbeBudget   Duration `json:"probe_budget"`   // сумма ожидаемых задержек проверок одного поиска (объем работы, а не время: см. planSearchWith)
+	MaxSites      int      `json:"max_sites"`      // не больше стольких сайтов за поиск, даже если бюджет позволяет
+	HeaderProfile string   `json:"header_profile"` // профиль заголовков (headers.go) или random
+	UserAgent     string   `json:"user_agent"`     // если задан, заменяет User-Agent профиля и отключает Sec-CH-UA*
+}
+
+// TelegramConfig — Bot API и проверка initData
+type TelegramConfig struct {
+	APIURL         string   `json:"api_url"`
+	InitDataMaxAge Duration `json:"init_data_max_age"`
+}
+
+// CORSConfig — политика CORS (см. cors.go)
+type CORSConfig struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           Duration `json:"max_age"`
+}
+
+// CircuitConfig — когда отключать сайт, который раз за разом не отвечает (см. circuit.go)
+type CircuitConfig struct {
+	Failures int      `json:"failures"` // сколько неудач подряд размыкают цепь; 0 — не отключать
+	Cooldown Duration `json:"cooldown"` // сколько ждать до пробной проверки
+}
+
+// ProxyConfig — группы прокси для проверок и их ротация (см. proxy.go).
+// Сайт выбирает группу полем proxy в data.json; без него действует default_policy.
+type ProxyConfig struct {
+	Groups        map[string][]ProxyURL `json:"groups,omitempty"` // группа -> http://, https:// или socks5:// адреса
+	Rotation      string                `json:"rotation"`         // round_robin или random
+	DefaultPolicy string                `json:"default_policy"`   // direct, proxy или proxy_group:NAME
+	MaxFailures   int                   `json:"max_failures"`     // неудач подряд до вывода прокси из ротации
+	Cooldown      Duration              `json:"cooldown"`         // на сколько выводить
+}
+
+// BreachConfig — источники данных об утечках (см. breach.go). Ключ HIBP — только HIBP_API_KEY.
+type BreachConfig struct {
+	HIBPURL    string   `json:"hibp_url"`    // HIBP-совместимый API v3; пусто — не опрашивать
+	LocalIndex string   `json:"local_index"` // индекс из gosearch breaches index; пусто — не использовать
+	RangeURL   string   `json:"range_url"`   // инстанс с индексом, который опрашивается через /breaches/range
+	Timeout    Duration `json:"timeout"`     // таймаут запроса к HIBP и range_url
+}
+
+// BatchConfig — пакетный поиск /search/batch (см. batch.go)
+type BatchConfig struct {
+	MaxUsernames int      `json:"max_usernames"` // сколько имен принимаем в одном запросе
+	Concurrency  int      `json:"concurrency"`   // запросов к сайтам одновременно на весь пакет
+	Timeout      Duration `json:"timeout"`       // дедлайн на весь пакет
+}
+
+// QuotaConfig — уровень по умолчанию, именованные уровни и назначения пользователей
+type QuotaConfig struct {
+	Default QuotaTier            `json:"default"`
+	Tiers   map[string]QuotaTier `json:"tiers,omitempty"`
+	Users   map[string]string    `json:"users,omitempty"` // Telegram user ID -> имя уровня
+	// TrustedProxy — чьим заголовкам с адресом клиента верить: vercel, forwarded или пусто (только RemoteAddr)
+	TrustedProxy string `json:"trusted_proxy,omitempty"`
+}
+
+// defaultConfig — значения, с которыми сервис работал до появления конфигурации
+func defaultConfig() *Config {
+	return &Config{
+		Sites: SitesConfig{
+			WatchInterval: Duration(10 * time.Second),
+		},
+		Search: SearchConfig{
+			ClientTimeout: Duration(9 * time.Second), // укладываемся в 10 секунд Vercel
+			SiteTimeout:   Duration(8 * time.Second),
+			ProbeBudget:   Duration(60 * time.Second), // около 30 сайтов без замеров задержки
+			MaxSites:      100,                        // раньше было 30; теперь обычно раньше срабатывает probe_budget
+			HeaderProfile: headerProfileRandom,
+		},
+		Telegram: TelegramConfig{
+			APIURL:         defaultTelegramAPIURL,
+			InitDataMaxAge: Duration(defaultInitDataMaxAge),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{githubPagesOrigin},
+			MaxAge:         Duration(defaultCORSMaxAge),
+		},
+		Quota: QuotaConfig{
+			Default: defaultQuotaTier,
+			Tiers:   map[string]QuotaTier{},
+			Users:   map[string]string{},
+		},
+		Circuit: CircuitConfig{
+			Failures: 5,
+			Cooldown: Duration(5 * time.Minute),
+		},
+		Proxy: ProxyConfig{
+			Groups:        map[string][]ProxyURL{},
+			Rotation:      ProxyRotationRoundRobin,
+			DefaultPolicy: ProxyPolicyDirect,
+			MaxFailures:   3,
+			Cooldown:      Duration(time.Minute),
+		},
+		Breach: BreachConfig{
+			Timeout: Duration(5 * time.Second),
+		},
+		Batch: BatchConfig{
+			MaxUsernames: 100,
+			Concurrency:  20,
+			Timeout:      Duration(2 * time.Minute),
+		},
+	}
+}
+
+// LoadConfig собирает конфигурацию из файла и окружения и проверяет ее
+func LoadConfig() (*Config, error) {
+	cfg := defaultConfig()
+	if path := os.Getenv("GOSEARCH_CONFIG"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile накладывает на cfg файл JSON или YAML (по расширению)
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		doc, err := parseSimpleYAML(data)
+		if err != nil {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		var doc interface{}
+		if json.Unmarshal(data, &doc) == nil {
+			if fieldErr := durationFieldError(doc, reflect.TypeOf(*c), ""); fieldErr != nil {
+				err = fieldErr
+			}
+		}
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+// durationFieldError находит в документе длительность, которую не разобрать, и называет ее ключ:
+// ошибку из UnmarshalJSON encoding/json возвращает без пути к полю
+func durationFieldError(doc interface{}, t reflect.Type, path string) error {
+	if t == reflect.TypeOf(Duration(0)) {
+		data, _ := json.Marshal(doc)
+		var d Duration
+		if err := d.UnmarshalJSON(data); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	}
+	switch value := doc.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				key, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+				if item, ok := value[key]; ok && item != nil {
+					if err := durationFieldError(item, t.Field(i).Type, strings.TrimPrefix(path+"."+key, ".")); err != nil {
+						return err
+					}
+				}
+			}
+		case reflect.Map:
+			for _, key := range sortedKeys(value) {
+				if err := durationFieldError(value[key], t.Elem(), path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice {
+			for i, item := range value {
+				if err := durationFieldError(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// loadEnv накладывает на cfg переменные окружения; некорректные значения — ошибка
+func (c *Config) loadEnv() error {
+	var errs []error
+	envDuration := func(name string, dst *Duration) {
+		if raw := os.Getenv(name); raw != "" {
+			v, err := parseDuration(raw)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*dst = Duration(v)
+		}
+	}
+	envInt := func(name string, dst *int) {
+		if raw := os.Getenv(name); raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*dst = v
+		}
+	}
+	envString := func(name string, dst *string) {
+		if raw := os.Getenv(name); raw != "" {
+			*dst = raw
+		}
+	}
+
+	envString("SITES_SOURCE", &c.Sites.Source)
+	envDuration("SITES_WATCH_INTERVAL", &c.Sites.WatchInterval)
+
+	envDuration("SEARCH_CLIENT_TIMEOUT", &c.Search.ClientTimeout)
+	envDuration("SEARCH_SITE_TIMEOUT", &c.Search.SiteTimeout)
+	envDuration("SEARCH_PROBE_BUDGET", &c.Search.ProbeBudget)
+	envInt("SEARCH_MAX_SITES", &c.Search.MaxSites)
+	envString("SEARCH_HEADER_PROFILE", &c.Search.HeaderProfile)
+	envString("SEARCH_USER_AGENT", &c.Search.UserAgent)
+
+	envString("TELEGRAM_API_URL", &c.Telegram.APIURL)
+	envDuration("TELEGRAM_INIT_DATA_MAX_AGE", &c.Telegram.InitDataMaxAge)
+
+	if raw := os.Getenv("CORS_ALLOWED_ORIGINS"); raw != "" {
+		c.CORS.AllowedOrigins = nil
+		for _, origin := range strings.Split(raw, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				c.CORS.AllowedOrigins = append(c.CORS.AllowedOrigins, origin)
+			}
+		}
+	}
+	if raw := os.Getenv("CORS_ALLOW_CREDENTIALS"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CORS_ALLOW_CREDENTIALS: %w", err))
+		}
+		c.CORS.AllowCredentials = v
+	}
+	envDuration("CORS_MAX_AGE", &c.CORS.MaxAge)
+
+	envInt("QUOTA_DAILY", &c.Quota.Default.Daily)
+	envInt("QUOTA_BURST", &c.Quota.Default.Burst)
+	envDuration("QUOTA_BURST_WINDOW", &c.Quota.Default.BurstWindow)
+	envInt("QUOTA_BATCH_BURST", &c.Quota.Default.BatchBurst)
+	// На Vercel (там задана VERCEL=1) заголовки с адресом ставит сама платформа
+	if os.Getenv("VERCEL") != "" && c.Quota.TrustedProxy == "" {
+		c.Quota.TrustedProxy = TrustedProxyVercel
+	}
+	envString("QUOTA_TRUSTED_PROXY", &c.Quota.TrustedProxy)
+	if raw := os.Getenv("QUOTA_TIERS"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &c.Quota.Tiers); err != nil {
+			errs = append(errs, fmt.Errorf("QUOTA_TIERS: %w", err))
+		}
+	}
+	if raw := os.Getenv("QUOTA_USERS"); raw != "" {
+		if c.Quota.Users == nil {
+			c.Quota.Users = map[string]string{}
+		}
+		for _, assignment := range strings.Split(raw, ",") {
+			id, tier, ok := strings.Cut(strings.TrimSpace(assignment), ":")
+			if !ok {
+				errs = append(errs, fmt.Errorf("QUOTA_USERS: expected id:tier, got %q", assignment))
+				continue
+			}
+			c.Quota.Users[id] = tier
+		}
+	}
+	envInt("CIRCUIT_FAILURES", &c.Circuit.Failures)
+	envDuration("CIRCUIT_COOLDOWN", &c.Circuit.Cooldown)
+
+	if raw := os.Getenv("PROXY_GROUPS"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &c.Proxy.Groups); err != nil {
+			errs = append(errs, fmt.Errorf("PROXY_GROUPS: %w", err))
+		}
+	}
+	if raw := os.Getenv("PROXY_URLS"); raw != "" {
+		if c.Proxy.Groups == nil {
+			c.Proxy.Groups = map[string][]ProxyURL{}
+		}
+		var urls []ProxyURL
+		for _, proxy := range strings.Split(raw, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				urls = append(urls, ProxyURL(proxy))
+			}
+		}
+		c.Proxy.Groups[defaultProxyGroup] = urls
+	}
+	envString("PROXY_ROTATION", &c.Proxy.Rotation)
+	envString("PROXY_DEFAULT_POLICY", &c.Proxy.DefaultPolicy)
+	envInt("PROXY_MAX_FAILURES", &c.Proxy.MaxFailures)
+	envDuration("PROXY_COOLDOWN", &c.Proxy.Cooldown)
+
+	envString("BREACH_HIBP_URL", &c.Breach.HIBPURL)
+	envString("BREACH_LOCAL_INDEX", &c.Breach.LocalIndex)
+	envString("BREACH_RANGE_URL", &c.Breach.RangeURL)
+	envDuration("BREACH_TIMEOUT", &c.Breach.Timeout)
+
+	envInt("BATCH_MAX_USERNAMES", &c.Batch.MaxUsernames)
+	envInt("BATCH_CONCURRENCY", &c.Batch.Concurrency)
+	envDuration("BATCH_TIMEOUT", &c.Batch.Timeout)
+	return errors.Join(errs...)
+}
+
+// Validate проверяет значения и сообщает обо всех ошибках сразу
+func (c *Config) Validate() error {
+	var errs []error
+	if strings.Contains(c.Sites.Source, "://") && !strings.HasPrefix(c.Sites.Source, "https://") {
+		errs = append(errs, fmt.Errorf("sites.source %q: only https URLs are allowed", c.Sites.Source))
+	}
+	if c.Sites.WatchInterval < 0 {
+		errs = append(errs, errors.New("sites.watch_interval must not be negative"))
+	}
+	if c.Search.ClientTimeout <= 0 {
+		errs = append(errs, errors.New("search.client_timeout must be positive"))
+	}
+	if c.Search.SiteTimeout <= 0 {
+		errs = append(errs, errors.New("search.site_timeout must be positive"))
+	}
+	if c.Search.ProbeBudget <= 0 {
+		errs = append(errs, errors.New("search.probe_budget must be positive"))
+	}
+	if c.Search.MaxSites < 1 {
+		errs = append(errs, errors.New("search.max_sites must be at least 1"))
+	}
+	if _, ok := headerProfiles[c.Search.HeaderProfile]; !ok && c.Search.HeaderProfile != headerProfileRandom {
+		errs = append(errs, fmt.Errorf("search.header_profile %q: expected one of %s", c.Search.HeaderProfile, strings.Join(headerProfileNames(), ", ")))
+	}
+
+	if u, err := url.Parse(c.Telegram.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("telegram.api_url %q is not an http(s) URL", c.Telegram.APIURL))
+	}
+	if c.Telegram.InitDataMaxAge < 0 {
+		errs = append(errs, errors.New("telegram.init_data_max_age must not be negative"))
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			// Браузер не примет "*" с credentials, а отражать любой Origin с cookie — это открыть API всем сайтам
+			if c.CORS.AllowCredentials {
+				errs = append(errs, errors.New(`cors.allowed_origins: "*" cannot be combined with allow_credentials`))
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			errs = append(errs, fmt.Errorf("cors.allowed_origins: %q is not an origin (scheme://host[:port])", origin))
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		errs = append(errs, errors.New("cors.max_age must not be negative"))
+	}
+
+	validateTier := func(name string, tier QuotaTier) {
+		if tier.Daily < 0 || tier.Burst < 0 || tier.BurstWindow < 0 || tier.BatchBurst < 0 {
+			errs = append(errs, fmt.Errorf("quota tier %s: limits must not be negative", name))
+		}
+		if (tier.Burst > 0 || tier.BatchBurst > 0) && tier.BurstWindow == 0 {
+			errs = append(errs, fmt.Errorf("quota tier %s: burst_window is required with burst and batch_burst", name))
+		}
+	}
+	validateTier("default", c.Quota.Default)
+	for name, tier := range c.Quota.Tiers {
+		validateTier(name, tier)
+	}
+	switch c.Quota.TrustedProxy {
+	case "", TrustedProxyVercel, TrustedProxyForwarded:
+	default:
+		errs = append(errs, fmt.Errorf("quota.trusted_proxy %q: expected %s, %s or empty", c.Quota.TrustedProxy, TrustedProxyVercel, TrustedProxyForwarded))
+	}
+	for id, tier := range c.Quota.Users {
+		if _, ok := c.Quota.Tiers[tier]; !ok {
+			errs = append(errs, fmt.Errorf("quota.users: unknown tier %q for user %s", tier, id))
+		}
+	}
+
+	if c.Circuit.Failures < 0 {
+		errs = append(errs, errors.New("circuit.failures must not be negative"))
+	}
+	if c.Circuit.Failures > 0 && c.Circuit.Cooldown <= 0 {
+		errs = append(errs, errors.New("circuit.cooldown must be positive"))
+	}
+
+	for name, urls := range c.Proxy.Groups {
+		for _, raw := range urls {
+			if _, err := parseProxyURL(string(raw)); err != nil {
+				errs = append(errs, fmt.Errorf("proxy.groups.%s: %w", name, err))
+			}
+		}
+	}
+	if c.Proxy.Rotation != ProxyRotationRoundRobin && c.Proxy.Rotation != ProxyRotationRandom {
+		errs = append(errs, fmt.Errorf("proxy.rotation %q: expected round_robin or random", c.Proxy.Rotation))
+	}
+	if group, err := parseProxyPolicy(c.Proxy.DefaultPolicy); err != nil {
+		errs = append(errs, fmt.Errorf("proxy.default_policy: %w", err))
+	} else if group != "" && len(c.Proxy.Groups[group]) == 0 {
+		errs = append(errs, fmt.Errorf("proxy.default_policy: group %q has no proxies", group))
+	}
+	if c.Proxy.MaxFailures < 0 || c.Proxy.Cooldown < 0 {
+		errs = append(errs, errors.New("proxy.max_failures and proxy.cooldown must not be negative"))
+	}
+
+	for name, raw := range map[string]string{"hibp_url": c.Breach.HIBPURL, "range_url": c.Breach.RangeURL} {
+		if raw == "" {
+			continue
+		}
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("breach.%s %q is not an http(s) URL", name, raw))
+		}
+	}
+	if c.Breach.Timeout <= 0 {
+		errs = append(errs, errors.New("breach.timeout must be positive"))
+	}
+
+	if c.Batch.MaxUsernames < 1 || c.Batch.Concurrency < 1 {
+		errs = append(errs, errors.New("batch.max_usernames and batch.concurrency must be at least 1"))
+	}
+	if c.Batch.Timeout <= 0 {
+		errs = append(errs, errors.New("batch.timeout must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// Duration — time.Duration, которая читается из JSON строкой вида "1m" или числом секунд (0 — выключено)
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	raw := string(data)
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		raw = text
+	}
+	parsed, err := parseDuration(raw)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s: expected a string like \"10s\" or a number of seconds", data)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// parseDuration принимает "1m30s" и число секунд без единиц ("0", "90", "1.5")
+func parseDuration(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil && !math.IsInf(seconds, 0) && !math.IsNaN(seconds) {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(raw)
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+var (
+	settingsOnce sync.Once
+	settings     *Config
+)
+
+// Settings возвращает действующую конфигурацию; при первом вызове загружает ее
+// и пишет в лог. Некорректная конфигурация останавливает процесс, как и битые данные сайтов.
+func Settings() *Config {
+	settingsOnce.Do(func() {
+		cfg, err := LoadConfig()
+		if err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+		report, _ := json.Marshal(cfg)
+		log.Printf("Effective configuration: %s", report)
+		settings = cfg
+	})
+	return settings
+}
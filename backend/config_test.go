@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurationUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want time.Duration
+	}{
+		{`"10s"`, 10 * time.Second},
+		{`"1m30s"`, 90 * time.Second},
+		{`"0"`, 0},
+		{`0`, 0},
+		{`90`, 90 * time.Second},
+		{`1.5`, 1500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		var d Duration
+		if err := d.UnmarshalJSON([]byte(tt.json)); err != nil {
+			t.Errorf("UnmarshalJSON(%s): %v", tt.json, err)
+			continue
+		}
+		if time.Duration(d) != tt.want {
+			t.Errorf("UnmarshalJSON(%s) = %v, want %v", tt.json, time.Duration(d), tt.want)
+		}
+	}
+	for _, bad := range []string{`"10x"`, `true`, `"inf"`, `[]`} {
+		var d Duration
+		if err := d.UnmarshalJSON([]byte(bad)); err == nil {
+			t.Errorf("UnmarshalJSON(%s) = %v, want error", bad, time.Duration(d))
+		}
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	write := func(name, content string) string {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg := defaultConfig()
+	if err := cfg.loadFile(write("config.yaml", "sites:\n  watch_interval: 0  # не следить\nsearch:\n  site_timeout: 5\n")); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	if cfg.Sites.WatchInterval != 0 || cfg.Search.SiteTimeout != Duration(5*time.Second) {
+		t.Errorf("watch_interval = %v, site_timeout = %v; want 0 and 5s", time.Duration(cfg.Sites.WatchInterval), time.Duration(cfg.Search.SiteTimeout))
+	}
+
+	// Ошибка называет поле, а не только тип
+	err := defaultConfig().loadFile(write("config.yaml", "sites:\n  watch_interval: soon\n"))
+	if err == nil || !strings.Contains(err.Error(), "sites.watch_interval") {
+		t.Errorf("loadFile with a bad duration: error %v, want it to name sites.watch_interval", err)
+	}
+}
+
+func TestLoadConfigExample(t *testing.T) {
+	t.Setenv("GOSEARCH_CONFIG", "config.example.yaml")
+	if _, err := LoadConfig(); err != nil {
+		t.Fatalf("config.example.yaml: %v", err)
+	}
+}
+
+func TestTrustedProxyEnv(t *testing.T) {
+	tests := []struct {
+		vercel, trusted string // VERCEL и QUOTA_TRUSTED_PROXY
+		want            string
+		valid           bool
+	}{
+		{"", "", "", true},
+		{"1", "", TrustedProxyVercel, true},
+		{"1", TrustedProxyForwarded, TrustedProxyForwarded, true},
+		{"", TrustedProxyForwarded, TrustedProxyForwarded, true},
+		{"", "cloudflare", "cloudflare", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("VERCEL", tt.vercel)
+		t.Setenv("QUOTA_TRUSTED_PROXY", tt.trusted)
+		cfg := defaultConfig()
+		if err := cfg.loadEnv(); err != nil {
+			t.Fatalf("loadEnv: %v", err)
+		}
+		if cfg.Quota.TrustedProxy != tt.want {
+			t.Errorf("VERCEL=%q QUOTA_TRUSTED_PROXY=%q: trusted_proxy %q, want %q", tt.vercel, tt.trusted, cfg.Quota.TrustedProxy, tt.want)
+		}
+		if err := cfg.Validate(); (err == nil) != tt.valid {
+			t.Errorf("trusted_proxy %q: Validate() = %v, want valid %v", cfg.Quota.TrustedProxy, err, tt.valid)
+		}
+	}
+}
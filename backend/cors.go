@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Origin GitHub Pages, где лежит Mini App из docs/
+const githubPagesOrigin = "https://ptspuf.github.io"
+
+const (
+	corsAllowedMethods = "GET, POST, OPTIONS"
+	corsAllowedHeaders = "Content-Type, Authorization, " + initDataHeader
+	defaultCORSMaxAge  = 10 * time.Minute
+)
+
+// corsPolicy — разрешенные источники и параметры ответов CORS
+type corsPolicy struct {
+	origins          map[string]bool
+	allowAll         bool // "*" в списке: только для локальной разработки
+	allowCredentials bool
+	maxAge           time.Duration
+}
+
+var (
+	corsOnce sync.Once
+	cors     *corsPolicy
+)
+
+// newCORSPolicy строит политику из cors-раздела конфигурации
+func newCORSPolicy(cfg CORSConfig) *corsPolicy {
+	policy := &corsPolicy{
+		origins:          map[string]bool{},
+		allowCredentials: cfg.AllowCredentials,
+		maxAge:           time.Duration(cfg.MaxAge),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		switch origin {
+		case "":
+		case "*":
+			policy.allowAll = true
+		default:
+			policy.origins[strings.ToLower(origin)] = true
+		}
+	}
+	return policy
+}
+
+func (p *corsPolicy) allowed(origin string) bool {
+	return p.allowAll || p.origins[strings.ToLower(origin)]
+}
+
+// applyCORS выставляет заголовки CORS и отвечает на preflight.
+// Запросы с чужим Origin отклоняются с 403; запросы без Origin (скрипты, вебхуки) проходят.
+// Возвращает false, если ответ уже записан.
+func applyCORS(w http.ResponseWriter, r *http.Request) bool {
+	corsOnce.Do(func() { cors = newCORSPolicy(Settings().CORS) })
+
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if !cors.allowed(origin) {
+		log.Printf("Rejected request from disallowed origin %q", origin)
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if cors.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	// Handle preflight requests
+	if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+		w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+		w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+		if cors.maxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cors.maxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useCORS подменяет раздел cors до конца теста
+func useCORS(t *testing.T, cfg CORSConfig) {
+	t.Helper()
+	settings := Settings()
+	saved := settings.CORS
+	settings.CORS = cfg
+	corsOnce, cors = sync.Once{}, nil
+	t.Cleanup(func() {
+		settings.CORS = saved
+		corsOnce, cors = sync.Once{}, nil
+	})
+}
+
+func TestApplyCORS(t *testing.T) {
+	useCORS(t, CORSConfig{AllowedOrigins: []string{"https://App.example/"}, MaxAge: Duration(10 * time.Minute)})
+
+	tests := []struct {
+		name      string
+		method    string
+		origin    string
+		preflight bool
+		status    int  // 0 — ответ не записан, запрос идет дальше
+		allowed   bool // есть Access-Control-Allow-Origin
+	}{
+		{"no origin", "GET", "", false, 0, false},
+		{"allowed origin", "GET", "https://app.example", false, 0, true},
+		{"denied origin", "GET", "https://evil.example", false, http.StatusForbidden, false},
+		{"preflight", "OPTIONS", "https://app.example", true, http.StatusNoContent, true},
+		{"denied preflight", "OPTIONS", "https://evil.example", true, http.StatusForbidden, false},
+		// OPTIONS без Access-Control-Request-Method — не preflight, его обрабатывает сам обработчик
+		{"plain OPTIONS", "OPTIONS", "https://app.example", false, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/search", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			w := httptest.NewRecorder()
+			proceed := applyCORS(w, r)
+
+			if proceed != (tt.status == 0) {
+				t.Fatalf("applyCORS = %v, want %v", proceed, tt.status == 0)
+			}
+			if tt.status != 0 && w.Code != tt.status {
+				t.Errorf("status %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); (got != "") != tt.allowed || (tt.allowed && got != tt.origin) {
+				t.Errorf("Access-Control-Allow-Origin = %q, want the origin: %v", got, tt.allowed)
+			}
+			if got := w.Header().Get("Vary"); got != "Origin" {
+				t.Errorf("Vary = %q, want Origin", got)
+			}
+			if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+				t.Error("credentials allowed without allow_credentials")
+			}
+		})
+	}
+}
+
+func TestCORSPreflightHeaders(t *testing.T) {
+	preflight := func() http.Header {
+		r := httptest.NewRequest("OPTIONS", "/search", nil)
+		r.Header.Set("Origin", "https://app.example")
+		r.Header.Set("Access-Control-Request-Method", "GET")
+		w := httptest.NewRecorder()
+		applyCORS(w, r)
+		return w.Header()
+	}
+
+	useCORS(t, CORSConfig{AllowedOrigins: []string{"https://app.example"}, AllowCredentials: true, MaxAge: Duration(90 * time.Second)})
+	header := preflight()
+	if got := header.Get("Access-Control-Allow-Methods"); got != corsAllowedMethods {
+		t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, corsAllowedMethods)
+	}
+	for _, name := range []string{"Authorization", "Content-Type", initDataHeader} {
+		if !strings.Contains(header.Get("Access-Control-Allow-Headers"), name) {
+			t.Errorf("Access-Control-Allow-Headers = %q, want %s", header.Get("Access-Control-Allow-Headers"), name)
+		}
+	}
+	if got := header.Get("Access-Control-Max-Age"); got != "90" {
+		t.Errorf("Access-Control-Max-Age = %q, want 90", got)
+	}
+	if got := header.Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+
+	// max_age: 0 — браузер решает сам, заголовок не отправляем
+	useCORS(t, CORSConfig{AllowedOrigins: []string{"https://app.example"}})
+	if got := preflight().Get("Access-Control-Max-Age"); got != "" {
+		t.Errorf("Access-Control-Max-Age = %q with max_age 0, want none", got)
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	useCORS(t, CORSConfig{AllowedOrigins: []string{"*"}})
+	r := httptest.NewRequest("GET", "/search", nil)
+	r.Header.Set("Origin", "http://localhost:5173")
+	w := httptest.NewRecorder()
+	if !applyCORS(w, r) || w.Header().Get("Access-Control-Allow-Origin") != "http://localhost:5173" {
+		t.Errorf("wildcard rejected a local origin: %v", w.Header())
+	}
+
+	cfg := defaultConfig()
+	cfg.CORS = CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "allow_credentials") {
+		t.Errorf(`Validate() = %v, want "*" with allow_credentials rejected`, err)
+	}
+	cfg.CORS.AllowedOrigins = []string{githubPagesOrigin}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v, credentials with an explicit origin must be allowed", err)
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// Что сайт принимает в URL (поле input в data.json)
+const (
+	SiteInputUsername = "username" // {} — имя пользователя; значение по умолчанию
+	SiteInputEmail    = "email"    // {email}, {md5}, {sha256} — адрес или его хэш, как у Gravatar
+)
+
+// Режимы поиска (параметр type=)
+const (
+	SearchTypeUsername = "username"
+	SearchTypeEmail    = "email"
+)
+
+// skipEmailOnly — причина пропуска email-источника в поиске по имени (SearchResult.Skipped)
+const skipEmailOnly = "email_only"
+
+// maxEmailCandidates — сколько имен из локальной части email проверяем; каждое умножает число запросов
+const maxEmailCandidates = 3
+
+// emailPlaceholders — подстановки в URL сайтов с input: email
+var emailPlaceholders = []string{"{email}", "{md5}", "{sha256}"}
+
+// siteInput — что подставлять в URL сайта
+func siteInput(site SiteInfo) string {
+	if site.Input == "" {
+		return SiteInputUsername
+	}
+	return site.Input
+}
+
+// expandSiteURL подставляет аккаунт в шаблон URL: имя вместо {}, для email-сайтов — адрес или хэш
+func expandSiteURL(template string, site SiteInfo, account string) string {
+	if siteInput(site) != SiteInputEmail {
+		return strings.Replace(template, "{}", account, 1)
+	}
+	email := strings.ToLower(strings.TrimSpace(account))
+	md5Sum := md5.Sum([]byte(email))
+	sha256Sum := sha256.Sum256([]byte(email))
+	return strings.NewReplacer(
+		"{email}", url.PathEscape(email),
+		"{md5}", hex.EncodeToString(md5Sum[:]),
+		"{sha256}", hex.EncodeToString(sha256Sum[:]),
+	).Replace(template)
+}
+
+// parseEmail принимает только голый адрес (без имени и угловых скобок) и приводит его к нижнему регистру
+func parseEmail(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	addr, err := mail.ParseAddress(raw)
+	if err != nil {
+		return "", err
+	}
+	if addr.Address != raw || addr.Name != "" {
+		return "", errors.New("expected a bare address like name@example.com")
+	}
+	return strings.ToLower(addr.Address), nil
+}
+
+// emailCandidates — имена, которые вероятно занял владелец адреса:
+// локальная часть без +метки, она же без разделителей и с "_" вместо точек
+func emailCandidates(email string) []string {
+	local, _, _ := strings.Cut(email, "@")
+	local, _, _ = strings.Cut(local, "+")
+	local = strings.ToLower(local)
+
+	variants := []string{
+		local,
+		strings.NewReplacer(".", "", "_", "", "-", "").Replace(local),
+		strings.ReplaceAll(local, ".", "_"),
+	}
+	var candidates []string
+	for _, variant := range variants {
+		if variant != "" && !containsString(candidates, variant) && len(candidates) < maxEmailCandidates {
+			candidates = append(candidates, variant)
+		}
+	}
+	return candidates
+}
+
+// logEmail — адрес для логов: начало SHA-256 и домен. Записи одного адреса можно
+// связать между собой, но сам адрес в логи не попадает.
+func logEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	_, domain, _ := strings.Cut(email, "@")
+	sum := sha256.Sum256([]byte(email))
+	return hex.EncodeToString(sum[:4]) + "@" + domain
+}
+
+// logURL — URL запроса для логов, где адреса в параметрах заменены на logEmail
+func logURL(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for _, values := range query {
+		for i, value := range values {
+			if _, err := parseEmail(value); err == nil {
+				values[i], redacted = logEmail(value), true
+			}
+		}
+	}
+	if !redacted {
+		return u.String()
+	}
+	copied := *u
+	copied.RawQuery = query.Encode()
+	return copied.String()
+}
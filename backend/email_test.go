@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailCandidates(t *testing.T) {
+	tests := []struct {
+		email string
+		want  []string
+	}{
+		{"a.b+tag@x", []string{"a.b", "ab", "a_b"}},
+		{"John.Smith@example.com", []string{"john.smith", "johnsmith", "john_smith"}},
+		{"alice@example.com", []string{"alice"}},
+		{"a_b-c@example.com", []string{"a_b-c", "abc"}},
+		{"first.middle.last+news@example.com", []string{"first.middle.last", "firstmiddlelast", "first_middle_last"}},
+		{"+tag@example.com", nil},
+	}
+	for _, tt := range tests {
+		if got := emailCandidates(tt.email); !equalStrings(got, tt.want) {
+			t.Errorf("emailCandidates(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestParseEmail(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // пусто — адрес отклоняется
+	}{
+		{"alice@example.com", "alice@example.com"},
+		{"  Alice@Example.COM ", "alice@example.com"},
+		{"a.b+tag@x", "a.b+tag@x"},
+		{"Alice <alice@example.com>", ""},
+		{"<alice@example.com>", ""},
+		{"alice", ""},
+		{"alice@", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseEmail(tt.raw)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("parseEmail(%q) = %q, want an error", tt.raw, got)
+		case tt.want != "" && (err != nil || got != tt.want):
+			t.Errorf("parseEmail(%q) = %q, %v; want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandSiteURL(t *testing.T) {
+	email := SiteInfo{Input: SiteInputEmail}
+	tests := []struct {
+		template string
+		site     SiteInfo
+		account  string
+		want     string
+	}{
+		// Хэш считается от адреса в нижнем регистре без пробелов, как у Gravatar
+		{"https://gravatar.example/{md5}", email, " Alice@Example.COM ", "https://gravatar.example/c160f8cc69a4f0bf2b0362752353d060"},
+		{"https://gravatar.example/{md5}", email, "alice@example.com", "https://gravatar.example/c160f8cc69a4f0bf2b0362752353d060"},
+		{"https://libravatar.example/{sha256}", email, "Alice@example.com", "https://libravatar.example/ff8d9819fc0e12bf0d24892e45987e249a28dce836a85cad60e28eaaa8c6d976"},
+		{"https://site.example/check/{email}", email, "a.b+tag@x", "https://site.example/check/a.b+tag@x"},
+		{"https://site.example/check/{email}", email, "a/b@x", "https://site.example/check/a%2Fb@x"},
+		{"https://site.example/{}", SiteInfo{}, "Alice", "https://site.example/Alice"},
+		{"https://site.example/{md5}/{}", SiteInfo{}, "alice", "https://site.example/{md5}/alice"},
+	}
+	for _, tt := range tests {
+		if got := expandSiteURL(tt.template, tt.site, tt.account); got != tt.want {
+			t.Errorf("expandSiteURL(%q, %q) = %q, want %q", tt.template, tt.account, got, tt.want)
+		}
+	}
+}
+
+func TestLogEmail(t *testing.T) {
+	if got, want := logEmail(" Alice@Example.com"), "ff8d9819@example.com"; got != want {
+		t.Errorf("logEmail = %q, want %q", got, want)
+	}
+
+	u, _ := url.Parse("/search?username=alice%40example.com&type=email&sites=GitHub")
+	got := logURL(u)
+	if strings.Contains(got, "alice") {
+		t.Errorf("logURL = %q, the address leaked", got)
+	}
+	if !strings.Contains(got, "username=ff8d9819%40example.com") || !strings.Contains(got, "sites=GitHub") {
+		t.Errorf("logURL = %q, want the address hashed and other parameters kept", got)
+	}
+	u, _ = url.Parse("/search?username=@alice")
+	if got := logURL(u); got != "/search?username=@alice" {
+		t.Errorf("logURL = %q, a username must be logged as is", got)
+	}
+}
+
+func TestHIBPErrorHidesAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	provider := newHIBPProvider(server.URL, "", 10*time.Millisecond)
+	_, err := provider.Lookup(context.Background(), "alice@example.com")
+	if err == nil || strings.Contains(err.Error(), "alice") {
+		t.Errorf("error %v, want a timeout without the address", err)
+	}
+}
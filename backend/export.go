@@ -0,0 +1,362 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// ExportResult — база в формате другого инструмента и то, что не удалось перевести
+type ExportResult struct {
+	Data     []byte     `json:"-"`
+	Exported int        `json:"exported"`
+	Skipped  []SiteNote `json:"skipped"` // не экспортированы: в формате нет такой проверки
+	Lossy    []SiteNote `json:"lossy"`   // экспортированы, но часть полей потеряна: по замечанию на поле
+}
+
+// ExportSites переводит нашу базу в JSON Sherlock или WhatsMyName.
+// Оба инструмента считают профиль найденным только при 2xx/200, поэтому
+// правила status_code в экспорте строже наших (любой статус, кроме errorCode).
+func ExportSites(format string, list []SiteInfo) (*ExportResult, error) {
+	c := &importCollector{}
+	var doc interface{}
+	switch format {
+	case FormatSherlock:
+		doc = exportSherlock(list, c)
+	case FormatWhatsMyName:
+		doc = exportWhatsMyName(list, c)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", format, err)
+	}
+	return &ExportResult{Data: data, Exported: len(list) - len(c.skipped), Skipped: c.skipped, Lossy: c.lossy}, nil
+}
+
+// VerifyExport импортирует результат обратно и сравнивает правила с исходными.
+// Возвращает расхождения по сайтам, о потерях в которых экспорт не предупредил.
+// Потеря отдельных полей (категорий, алиасов и т.п.) правило не меняет, поэтому такие сайты тоже сверяются.
+func VerifyExport(format string, list []SiteInfo, result *ExportResult) ([]SiteNote, error) {
+	back, err := convertSites(format, result.Data)
+	if err != nil {
+		return nil, err
+	}
+	flagged := map[string]bool{}
+	for _, note := range append(result.Skipped, result.Lossy...) {
+		if note.Field == "" {
+			flagged[note.Site] = true
+		}
+	}
+	imported := map[string]SiteInfo{}
+	for _, site := range back.sites {
+		imported[site.Name] = site
+	}
+
+	var diffs []SiteNote
+	for _, site := range list {
+		if flagged[site.Name] {
+			continue
+		}
+		got, ok := imported[site.Name]
+		if !ok {
+			diffs = append(diffs, SiteNote{Site: site.Name, Message: "missing after re-import"})
+			continue
+		}
+		if diff := siteRuleDiff(site, got); diff != "" {
+			diffs = append(diffs, SiteNote{Site: site.Name, Message: diff})
+		}
+	}
+	return diffs, nil
+}
+
+// siteRuleDiff сравнивает поля, влияющие на проверку
+func siteRuleDiff(want, got SiteInfo) string {
+	wantCode, _ := errorCodeInt(want.ErrorCode)
+	gotCode, _ := errorCodeInt(got.ErrorCode)
+	switch {
+	case want.BaseURL != got.BaseURL:
+		return fmt.Sprintf("base_url %q became %q", want.BaseURL, got.BaseURL)
+	case want.URLProbe != got.URLProbe:
+		return fmt.Sprintf("url_probe %q became %q", want.URLProbe, got.URLProbe)
+	case want.ErrorType != got.ErrorType:
+		return fmt.Sprintf("errorType %s became %s", want.ErrorType, got.ErrorType)
+	case wantCode != gotCode:
+		return fmt.Sprintf("errorCode %d became %d", wantCode, gotCode)
+	case want.ErrorMsg != got.ErrorMsg:
+		return fmt.Sprintf("errorMsg %q became %q", want.ErrorMsg, got.ErrorMsg)
+	case len(want.Cookies)+len(got.Cookies) > 0 && !reflect.DeepEqual(want.Cookies, got.Cookies):
+		return "cookies changed"
+	}
+	return ""
+}
+
+// exportCommon отмечает поля, которых нет в обоих форматах
+func exportCommon(site SiteInfo, c *importCollector) {
+	if site.FollowRedirects != nil && !*site.FollowRedirects {
+		c.loseField(site.Name, "follow_redirects", "follow_redirects false is not expressible; redirects will be followed")
+	}
+	if len(site.KnownAbsent) > 0 {
+		c.loseField(site.Name, "known_absent", "known_absent %v is dropped", site.KnownAbsent)
+	}
+	if len(site.Aliases) > 0 {
+		c.loseField(site.Name, "aliases", "aliases %v are dropped", site.Aliases)
+	}
+	if site.Popularity != 0 {
+		c.loseField(site.Name, "popularity", "popularity %d is dropped", site.Popularity)
+	}
+	if site.Proxy != "" {
+		c.loseField(site.Name, "proxy", "proxy %s is dropped; requests go direct", site.Proxy)
+	}
+	if site.HeaderProfile != "" {
+		c.loseField(site.Name, "header_profile", "header_profile %s is dropped", site.HeaderProfile)
+	}
+}
+
+// cookieHeader собирает cookies в заголовок Cookie
+func cookieHeader(cookies []SiteCookie) map[string]string {
+	if len(cookies) == 0 {
+		return nil
+	}
+	parts := make([]string, len(cookies))
+	for i, cookie := range cookies {
+		parts[i] = cookie.Name + "=" + cookie.Value
+	}
+	return map[string]string{"Cookie": strings.Join(parts, "; ")}
+}
+
+// cookiesFromHeaders — обратное преобразование; dropped сообщает о других заголовках
+func cookiesFromHeaders(headers map[string]string) (cookies []SiteCookie, dropped bool) {
+	for _, key := range sortedKeys(headers) {
+		if !strings.EqualFold(key, "Cookie") {
+			dropped = true
+			continue
+		}
+		for _, part := range strings.Split(headers[key], ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if ok && name != "" {
+				cookies = append(cookies, SiteCookie{Name: name, Value: value})
+			}
+		}
+	}
+	return cookies, dropped
+}
+
+// --- Sherlock ---
+
+type sherlockExport struct {
+	URL       string            `json:"url"`
+	URLMain   string            `json:"urlMain"`
+	URLProbe  string            `json:"urlProbe,omitempty"`
+	ErrorType string            `json:"errorType"`
+	ErrorCode int               `json:"errorCode,omitempty"`
+	ErrorMsg  string            `json:"errorMsg,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Claimed   string            `json:"username_claimed,omitempty"`
+}
+
+func exportSherlock(list []SiteInfo, c *importCollector) map[string]interface{} {
+	doc := map[string]interface{}{
+		"$schema": "https://raw.githubusercontent.com/sherlock-project/sherlock/master/sherlock_project/resources/data.schema.json",
+	}
+	for _, site := range list {
+		if siteInput(site) == SiteInputEmail {
+			c.skip(site.Name, "Sherlock has no email-based checks")
+			continue
+		}
+		s := sherlockExport{
+			URL:      site.BaseURL,
+			URLMain:  siteMainURL(site.BaseURL),
+			URLProbe: site.URLProbe,
+			Headers:  cookieHeader(site.Cookies),
+		}
+		switch site.ErrorType {
+		case "status_code":
+			code, ok := errorCodeInt(site.ErrorCode)
+			if !ok {
+				c.skip(site.Name, "status_code without a confirmed errorCode is not checked")
+				continue
+			}
+			s.ErrorType = "status_code"
+			s.ErrorCode = code
+			c.loseField(site.Name, "errorCode", "Sherlock counts only 2xx as found; here any status other than %d is found", code)
+		case "errorMsg":
+			s.ErrorType = "message"
+			s.ErrorMsg = site.ErrorMsg
+		case "profilePresence":
+			c.skip(site.Name, "Sherlock has no presence-string check")
+			continue
+		default:
+			c.skip(site.Name, "errorType %s is not checked", site.ErrorType)
+			continue
+		}
+		if len(site.KnownPresent) > 0 {
+			s.Claimed = site.KnownPresent[0]
+			if len(site.KnownPresent) > 1 {
+				c.loseField(site.Name, "known_present", "only the first known_present username fits username_claimed")
+			}
+		}
+		if len(site.Categories) > 0 {
+			c.loseField(site.Name, "categories", "categories %v are dropped", site.Categories)
+		}
+		if len(site.Tags) > 0 {
+			c.loseField(site.Name, "tags", "tags %v are dropped", site.Tags)
+		}
+		exportCommon(site, c)
+		doc[site.Name] = s
+	}
+	return doc
+}
+
+// siteMainURL — главная страница сайта для urlMain
+func siteMainURL(template string) string {
+	u, err := url.Parse(strings.Replace(template, "{}", "username", -1))
+	if err != nil {
+		return template
+	}
+	return u.Scheme + "://" + u.Host + "/"
+}
+
+// --- WhatsMyName ---
+
+type wmnExport struct {
+	Name      string            `json:"name"`
+	URICheck  string            `json:"uri_check"`
+	URIPretty string            `json:"uri_pretty,omitempty"`
+	ECode     int               `json:"e_code"`
+	EString   string            `json:"e_string"`
+	MCode     int               `json:"m_code"`
+	MString   string            `json:"m_string"`
+	Known     []string          `json:"known"`
+	Cat       string            `json:"cat"`
+	Valid     bool              `json:"valid"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// wmnCategories — наши категории в терминах cat WhatsMyName; первая подходящая выигрывает,
+// остальное уходит в "misc" (WhatsMyName требует cat у каждого сайта)
+var wmnCategories = map[string]string{
+	"social":    "social",
+	"fediverse": "social",
+	"dev":       "coding",
+	"security":  "tech",
+	"gaming":    "gaming",
+	"music":     "music",
+	"video":     "video",
+	"art":       "art",
+	"photo":     "images",
+	"blog":      "blog",
+	"news":      "news",
+	"finance":   "finance",
+	"shopping":  "shopping",
+	"jobs":      "business",
+	"dating":    "dating",
+	"sports":    "hobby",
+	"travel":    "hobby",
+	"books":     "hobby",
+}
+
+func wmnCategory(categories []string) string {
+	for _, category := range categories {
+		if cat, ok := wmnCategories[category]; ok {
+			return cat
+		}
+	}
+	return "misc"
+}
+
+func exportWhatsMyName(list []SiteInfo, c *importCollector) map[string]interface{} {
+	toWMN := func(template string) string { return strings.ReplaceAll(template, "{}", "{account}") }
+
+	sites := []wmnExport{}
+	categories := map[string]bool{}
+	for _, site := range list {
+		if siteInput(site) == SiteInputEmail {
+			c.skip(site.Name, "WhatsMyName has no email-based checks")
+			continue
+		}
+		s := wmnExport{
+			Name:     site.Name,
+			URICheck: toWMN(site.BaseURL),
+			ECode:    http.StatusOK,
+			MCode:    http.StatusOK,
+			Known:    append([]string{}, site.KnownPresent...),
+			Cat:      wmnCategory(site.Categories),
+			Valid:    true,
+			Headers:  cookieHeader(site.Cookies),
+		}
+		if site.URLProbe != "" {
+			s.URICheck = toWMN(site.URLProbe)
+			s.URIPretty = toWMN(site.BaseURL)
+		}
+
+		// WMN: найден, если статус == e_code и тело содержит e_string;
+		// не найден, если статус == m_code и тело содержит m_string
+		switch site.ErrorType {
+		case "status_code":
+			code, ok := errorCodeInt(site.ErrorCode)
+			if !ok {
+				c.skip(site.Name, "status_code without a confirmed errorCode is not checked")
+				continue
+			}
+			if code == http.StatusOK {
+				c.skip(site.Name, "errorCode 200 cannot be told apart from e_code 200")
+				continue
+			}
+			s.MCode = code
+			c.loseField(site.Name, "errorCode", "WhatsMyName counts only 200 as found; here any status other than %d is found", code)
+		case "profilePresence":
+			s.EString = site.ErrorMsg
+		case "errorMsg":
+			// Без e_string WMN сочтет найденным любой ответ 200
+			s.MString = site.ErrorMsg
+			s.Valid = false
+			c.lose(site.Name, "no e_string to detect a profile; exported with valid: false")
+		default:
+			c.skip(site.Name, "errorType %s is not checked", site.ErrorType)
+			continue
+		}
+		if len(site.Categories) > 1 || (len(site.Categories) == 1 && s.Cat == "misc") {
+			c.loseField(site.Name, "categories", "categories %v are exported as cat %q", site.Categories, s.Cat)
+		}
+		if len(site.Tags) > 0 {
+			c.loseField(site.Name, "tags", "tags %v are dropped", site.Tags)
+		}
+		exportCommon(site, c)
+		categories[s.Cat] = true
+		sites = append(sites, s)
+	}
+
+	return map[string]interface{}{
+		"categories": sortedKeys(categories),
+		"sites":      sites,
+	}
+}
+
+// handleExport отдает текущую базу в формате ?format=sherlock|whatsmyname
+func handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = FormatWhatsMyName
+	}
+	snapshot := loadSites()
+	result, err := ExportSites(format, snapshot.Sites)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gosearch-%s-%s.json"`, format, snapshot.Version))
+	w.Header().Set("X-Sites-Version", snapshot.Version)
+	w.Header().Set("X-Export-Skipped", fmt.Sprint(len(result.Skipped)))
+	w.Header().Set("X-Export-Lossy", fmt.Sprint(len(result.Lossy)))
+	w.Write(result.Data)
+}
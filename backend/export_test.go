@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func exportTestSites() []SiteInfo {
+	noRedirects := false
+	return []SiteInfo{
+		{Name: "Status", BaseURL: "https://status.example/{}", ErrorType: "status_code", ErrorCode: 404, KnownPresent: []string{"alice"}},
+		{Name: "Probe", BaseURL: "https://probe.example/u/{}", URLProbe: "https://probe.example/api/{}", ErrorType: "status_code", ErrorCode: 410},
+		{Name: "Message", BaseURL: "https://message.example/{}", ErrorType: "errorMsg", ErrorMsg: "User not found"},
+		{Name: "Presence", BaseURL: "https://presence.example/{}", ErrorType: "profilePresence", ErrorMsg: "Joined"},
+		{Name: "Cookies", BaseURL: "https://cookies.example/{}", ErrorType: "status_code", ErrorCode: 404,
+			Cookies: []SiteCookie{{Name: "consent", Value: "yes"}, {Name: "lang", Value: "en"}}},
+		{Name: "Redirect", BaseURL: "https://redirect.example/{}", ErrorType: "status_code", ErrorCode: 302, FollowRedirects: &noRedirects},
+		{Name: "Unconfirmed", BaseURL: "https://unconfirmed.example/{}", ErrorType: "unknown"},
+		// Поля, которых нет ни в Sherlock, ни в WhatsMyName; правило при этом переводится как есть
+		{Name: "Metadata", BaseURL: "https://metadata.example/{}", ErrorType: "errorMsg", ErrorMsg: "No such user",
+			Categories: []string{"dev", "security"}, Tags: []string{"open-source"}, Aliases: []string{"meta"}, Popularity: 40,
+			KnownPresent: []string{"alice", "bob"}, KnownAbsent: []string{"ghost"}, Proxy: ProxyPolicyDirect, HeaderProfile: "firefox"},
+		{Name: "Email", Input: SiteInputEmail, BaseURL: "https://email.example/{md5}", ErrorType: "status_code", ErrorCode: 404},
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	tests := []struct {
+		format  string
+		skipped []string
+		lossy   []string // site:field; без поля — правило передано не полностью
+	}{
+		{FormatSherlock, []string{"Presence", "Unconfirmed", "Email"}, []string{
+			"Status:errorCode", "Probe:errorCode", "Cookies:errorCode", "Redirect:errorCode", "Redirect:follow_redirects",
+			"Metadata:known_present", "Metadata:categories", "Metadata:tags", "Metadata:known_absent",
+			"Metadata:aliases", "Metadata:popularity", "Metadata:proxy", "Metadata:header_profile",
+		}},
+		{FormatWhatsMyName, []string{"Unconfirmed", "Email"}, []string{
+			"Status:errorCode", "Probe:errorCode", "Message:", "Cookies:errorCode", "Redirect:errorCode", "Redirect:follow_redirects",
+			"Metadata:", "Metadata:categories", "Metadata:tags", "Metadata:known_absent",
+			"Metadata:aliases", "Metadata:popularity", "Metadata:proxy", "Metadata:header_profile",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			list := exportTestSites()
+			result, err := ExportSites(tt.format, list)
+			if err != nil {
+				t.Fatalf("ExportSites: %v", err)
+			}
+			if got := noteSites(result.Skipped); !equalStrings(got, tt.skipped) {
+				t.Errorf("skipped = %v, want %v", got, tt.skipped)
+			}
+			if got := noteFields(result.Lossy); !equalStrings(got, tt.lossy) {
+				t.Errorf("lossy = %v, want %v", got, tt.lossy)
+			}
+			if want := len(list) - len(tt.skipped); result.Exported != want {
+				t.Errorf("exported = %d, want %d", result.Exported, want)
+			}
+
+			// import(export(x)) должен вернуть те же правила для всего, что не пропущено и не потеряло правило целиком;
+			// сайты, у которых потеряны только отдельные поля, тоже сверяются
+			diffs, err := VerifyExport(tt.format, list, result)
+			if err != nil {
+				t.Fatalf("VerifyExport: %v", err)
+			}
+			for _, diff := range diffs {
+				t.Errorf("%s: %s", diff.Site, diff.Message)
+			}
+		})
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	if _, err := ExportSites("maigret", exportTestSites()); err == nil {
+		t.Error("ExportSites(maigret): want an error")
+	}
+}
+
+func TestFetchProfileCookiesAndRedirects(t *testing.T) {
+	var cookie string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved/alice":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/login":
+			w.WriteHeader(http.StatusOK)
+		default:
+			cookie = r.Header.Get("Cookie")
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	site := statusSite("Cookies", server.URL+"/u/{}")
+	site.Cookies = []SiteCookie{{Name: "consent", Value: "yes"}, {Name: "lang", Value: "en"}}
+	if _, _, err := fetchProfile(context.Background(), server.Client(), site, HeaderProfile{}, "alice"); err != nil {
+		t.Fatalf("fetchProfile: %v", err)
+	}
+	if want := "consent=yes; lang=en"; cookie != want {
+		t.Errorf("Cookie = %q, want %q", cookie, want)
+	}
+
+	noRedirects, follow := false, true
+	tests := []struct {
+		follow *bool
+		want   int
+	}{
+		{nil, http.StatusOK},
+		{&follow, http.StatusOK},
+		{&noRedirects, http.StatusFound},
+	}
+	for _, tt := range tests {
+		site := statusSite("Redirect", server.URL+"/moved/{}")
+		site.FollowRedirects = tt.follow
+		status, _, err := fetchProfile(context.Background(), server.Client(), site, HeaderProfile{}, "alice")
+		if err != nil {
+			t.Fatalf("fetchProfile: %v", err)
+		}
+		if status != tt.want {
+			t.Errorf("follow_redirects %v: status %d, want %d", tt.follow != nil && *tt.follow, status, tt.want)
+		}
+	}
+}
+
+func noteSites(notes []SiteNote) []string {
+	var sites []string
+	for _, note := range notes {
+		sites = append(sites, note.Site)
+	}
+	return sites
+}
+
+func noteFields(notes []SiteNote) []string {
+	var fields []string
+	for _, note := range notes {
+		fields = append(fields, note.Site+":"+note.Field)
+	}
+	return fields
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
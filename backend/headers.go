@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// headerProfileRandom — в каждом поиске случайный настольный профиль
+const headerProfileRandom = "random"
+
+// HeaderProfile — согласованный набор заголовков одного браузера.
+// Accept-Encoding не задаем: его выставляет net/http и сам распаковывает gzip.
+type HeaderProfile struct {
+	Name    string
+	Mobile  bool // мобильные профили выбираются только явно
+	Headers map[string]string
+}
+
+// Заголовки навигации верхнего уровня, одинаковые у Chromium-браузеров
+func chromiumHeaders(userAgent, brand, platform string, mobile bool) map[string]string {
+	mobileHint := "?0"
+	if mobile {
+		mobileHint = "?1"
+	}
+	return map[string]string{
+		"User-Agent":                userAgent,
+		"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
+		"Accept-Language":           "en-US,en;q=0.9",
+		"Sec-CH-UA":                 `"Chromium";v="140", "Not=A?Brand";v="24", "` + brand + `";v="140"`,
+		"Sec-CH-UA-Mobile":          mobileHint,
+		"Sec-CH-UA-Platform":        `"` + platform + `"`,
+		"Sec-Fetch-Dest":            "document",
+		"Sec-Fetch-Mode":            "navigate",
+		"Sec-Fetch-Site":            "none",
+		"Sec-Fetch-User":            "?1",
+		"Upgrade-Insecure-Requests": "1",
+	}
+}
+
+var headerProfiles = map[string]HeaderProfile{
+	"chrome-windows": {
+		Name:    "chrome-windows",
+		Headers: chromiumHeaders("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36", "Google Chrome", "Windows", false),
+	},
+	"chrome-macos": {
+		Name:    "chrome-macos",
+		Headers: chromiumHeaders("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36", "Google Chrome", "macOS", false),
+	},
+	"edge-windows": {
+		Name:    "edge-windows",
+		Headers: chromiumHeaders("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36 Edg/140.0.0.0", "Microsoft Edge", "Windows", false),
+	},
+	"chrome-android": {
+		Name:    "chrome-android",
+		Mobile:  true,
+		Headers: chromiumHeaders("Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Mobile Safari/537.36", "Google Chrome", "Android", true),
+	},
+	"firefox-windows": {
+		Name: "firefox-windows",
+		Headers: map[string]string{
+			"User-Agent":                "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:143.0) Gecko/20100101 Firefox/143.0",
+			"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			"Accept-Language":           "en-US,en;q=0.5",
+			"Sec-Fetch-Dest":            "document",
+			"Sec-Fetch-Mode":            "navigate",
+			"Sec-Fetch-Site":            "none",
+			"Sec-Fetch-User":            "?1",
+			"Upgrade-Insecure-Requests": "1",
+		},
+	},
+	"firefox-linux": {
+		Name: "firefox-linux",
+		Headers: map[string]string{
+			"User-Agent":                "Mozilla/5.0 (X11; Linux x86_64; rv:143.0) Gecko/20100101 Firefox/143.0",
+			"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			"Accept-Language":           "en-US,en;q=0.5",
+			"Sec-Fetch-Dest":            "document",
+			"Sec-Fetch-Mode":            "navigate",
+			"Sec-Fetch-Site":            "none",
+			"Sec-Fetch-User":            "?1",
+			"Upgrade-Insecure-Requests": "1",
+		},
+	},
+	"safari-macos": {
+		Name: "safari-macos",
+		Headers: map[string]string{
+			"User-Agent":      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.6 Safari/605.1.15",
+			"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			"Accept-Language": "en-US,en;q=0.9",
+			"Sec-Fetch-Dest":  "document",
+			"Sec-Fetch-Mode":  "navigate",
+			"Sec-Fetch-Site":  "none",
+		},
+	},
+}
+
+// pickHeaderProfile возвращает профиль по имени; "random" и пустое имя — по search.header_profile
+func pickHeaderProfile(name string) HeaderProfile {
+	if name == "" {
+		name = Settings().Search.HeaderProfile
+	}
+	if profile, ok := headerProfiles[name]; ok {
+		return profile
+	}
+	var desktop []string
+	for _, key := range sortedKeys(headerProfiles) {
+		if !headerProfiles[key].Mobile {
+			desktop = append(desktop, key)
+		}
+	}
+	return headerProfiles[desktop[rand.Intn(len(desktop))]]
+}
+
+// siteHeaderProfile — закрепленный за сайтом профиль важнее выбранного для поиска
+func siteHeaderProfile(site SiteInfo, search HeaderProfile) HeaderProfile {
+	if profile, ok := headerProfiles[site.HeaderProfile]; ok {
+		return profile
+	}
+	return search
+}
+
+// apply выставляет заголовки профиля; search.user_agent, если задан, заменяет User-Agent.
+// Sec-CH-UA* описывают браузер профиля и с чужим User-Agent противоречили бы ему, поэтому
+// вместе с заменой их не отправляем.
+func (p HeaderProfile) apply(req *http.Request) {
+	ua := Settings().Search.UserAgent
+	for key, value := range p.Headers {
+		if ua != "" && strings.HasPrefix(http.CanonicalHeaderKey(key), "Sec-Ch-Ua") {
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+	if ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+}
+
+// headerProfileNames — имена для подсказок и сообщений об ошибках
+func headerProfileNames() []string {
+	names := append(sortedKeys(headerProfiles), headerProfileRandom)
+	sort.Strings(names)
+	return names
+}
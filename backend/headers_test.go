@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// useUserAgent подменяет search.user_agent до конца теста
+func useUserAgent(t *testing.T, ua string) {
+	t.Helper()
+	cfg := Settings()
+	saved := cfg.Search.UserAgent
+	cfg.Search.UserAgent = ua
+	t.Cleanup(func() { cfg.Search.UserAgent = saved })
+}
+
+func TestHeaderProfilesConsistent(t *testing.T) {
+	for name, profile := range headerProfiles {
+		if profile.Name != name {
+			t.Errorf("%s: Name = %q", name, profile.Name)
+		}
+		ua := profile.Headers["User-Agent"]
+		if ua == "" || profile.Headers["Accept"] == "" || profile.Headers["Accept-Language"] == "" {
+			t.Errorf("%s: missing User-Agent, Accept or Accept-Language", name)
+		}
+		if _, ok := profile.Headers["Accept-Encoding"]; ok {
+			t.Errorf("%s: Accept-Encoding is left to net/http", name)
+		}
+		if profile.Mobile != strings.Contains(ua, "Mobile") {
+			t.Errorf("%s: Mobile = %v for %q", name, profile.Mobile, ua)
+		}
+
+		// Client hints отправляют только Chromium-браузеры, и они должны совпадать с User-Agent
+		hints, chromium := profile.Headers["Sec-CH-UA"], strings.Contains(ua, "Chrome/")
+		if chromium != (hints != "") {
+			t.Errorf("%s: Sec-CH-UA %q for %q", name, hints, ua)
+			continue
+		}
+		if !chromium {
+			continue
+		}
+		if strings.Contains(ua, "Edg/") != strings.Contains(hints, "Microsoft Edge") {
+			t.Errorf("%s: brand in %q does not match %q", name, hints, ua)
+		}
+		platform := strings.Trim(profile.Headers["Sec-CH-UA-Platform"], `"`)
+		want := map[string]string{"Windows": "Windows NT", "macOS": "Mac OS X", "Android": "Android"}[platform]
+		if want == "" || !strings.Contains(ua, want) {
+			t.Errorf("%s: Sec-CH-UA-Platform %q does not match %q", name, platform, ua)
+		}
+		if mobile := profile.Headers["Sec-CH-UA-Mobile"] == "?1"; mobile != profile.Mobile {
+			t.Errorf("%s: Sec-CH-UA-Mobile %q, Mobile = %v", name, profile.Headers["Sec-CH-UA-Mobile"], profile.Mobile)
+		}
+	}
+}
+
+func TestPickHeaderProfile(t *testing.T) {
+	cfg := Settings()
+	saved := cfg.Search.HeaderProfile
+	t.Cleanup(func() { cfg.Search.HeaderProfile = saved })
+
+	cfg.Search.HeaderProfile = "firefox-linux"
+	if got := pickHeaderProfile("chrome-android").Name; got != "chrome-android" {
+		t.Errorf("explicit profile: %s, want chrome-android", got)
+	}
+	if got := pickHeaderProfile("").Name; got != "firefox-linux" {
+		t.Errorf("empty name: %s, want search.header_profile firefox-linux", got)
+	}
+
+	// random выбирает только настольные профили
+	cfg.Search.HeaderProfile = headerProfileRandom
+	for _, name := range []string{"", headerProfileRandom} {
+		for i := 0; i < 50; i++ {
+			if profile := pickHeaderProfile(name); profile.Mobile || profile.Name == "" {
+				t.Fatalf("pickHeaderProfile(%q) = %s, want a desktop profile", name, profile.Name)
+			}
+		}
+	}
+}
+
+func TestSiteHeaderProfile(t *testing.T) {
+	search := headerProfiles["chrome-windows"]
+	site := statusSite("Pinned", "https://pinned.example/{}")
+	if got := siteHeaderProfile(site, search).Name; got != "chrome-windows" {
+		t.Errorf("no pinned profile: %s, want the search profile", got)
+	}
+	site.HeaderProfile = "safari-macos"
+	if got := siteHeaderProfile(site, search).Name; got != "safari-macos" {
+		t.Errorf("pinned profile: %s, want safari-macos", got)
+	}
+}
+
+func TestHeaderProfileApply(t *testing.T) {
+	profile := headerProfiles["chrome-windows"]
+
+	r := httptest.NewRequest("GET", "https://example.com/alice", nil)
+	profile.apply(r)
+	for key, value := range profile.Headers {
+		if got := r.Header.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	// Свой User-Agent: подсказки Chrome ему противоречили бы и не отправляются
+	useUserAgent(t, "gosearch/1.0")
+	r = httptest.NewRequest("GET", "https://example.com/alice", nil)
+	profile.apply(r)
+	if got := r.Header.Get("User-Agent"); got != "gosearch/1.0" {
+		t.Errorf("User-Agent = %q, want search.user_agent", got)
+	}
+	for key := range r.Header {
+		if strings.HasPrefix(key, http.CanonicalHeaderKey("Sec-CH-UA")) {
+			t.Errorf("%s sent with a custom User-Agent", key)
+		}
+	}
+	for _, key := range []string{"Accept", "Accept-Language", "Sec-Fetch-Mode"} {
+		if got := r.Header.Get(key); got != profile.Headers[key] {
+			t.Errorf("%s = %q, want the profile value", key, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// useTestSites подменяет базу сайтов на sites до конца теста. База грузится лениво,
+// как в проде: первым loadSites() из кода или теста.
+func useTestSites(t *testing.T, sites ...SiteInfo) {
+	t.Helper()
+	data, err := json.Marshal(sites)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "sites.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Settings()
+	source, snapshot := cfg.Sites.Source, currentSites.Load()
+	cfg.Sites.Source = path
+	once = sync.Once{}
+	currentSites.Store(nil)
+	t.Cleanup(func() {
+		cfg.Sites.Source = source
+		once = sync.Once{}
+		currentSites.Store(snapshot)
+	})
+}
+
+// statusSite — сайт, где профиль есть, если base_url отвечает не 404
+func statusSite(name, baseURL string) SiteInfo {
+	return SiteInfo{Name: name, BaseURL: baseURL, ErrorType: "status_code", ErrorCode: 404}
+}
@@ -0,0 +1,431 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Форматы, из которых умеем импортировать правила
+const (
+	FormatSherlock    = "sherlock"
+	FormatWhatsMyName = "whatsmyname"
+	FormatMaigret     = "maigret"
+)
+
+// SiteNote — замечание импорта или экспорта по одному сайту
+type SiteNote struct {
+	Site    string `json:"site"`
+	Field   string `json:"field,omitempty"` // поле, которое не удалось передать; пусто — замечание о правиле целиком
+	Message string `json:"message"`
+}
+
+// ImportResult — новые сайты, пропущенные сайты и потери при переводе правил
+type ImportResult struct {
+	Imported []SiteInfo `json:"imported"`
+	Skipped  []SiteNote `json:"skipped"` // не импортированы: нечего перевести или дубликат
+	Lossy    []SiteNote `json:"lossy"`   // импортированы, но часть семантики потеряна
+}
+
+// importCollector собирает результаты конвертера одного формата
+type importCollector struct {
+	sites   []SiteInfo
+	skipped []SiteNote
+	lossy   []SiteNote
+}
+
+func (c *importCollector) skip(site, format string, args ...interface{}) {
+	c.skipped = append(c.skipped, SiteNote{Site: site, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *importCollector) lose(site, format string, args ...interface{}) {
+	c.lossy = append(c.lossy, SiteNote{Site: site, Message: fmt.Sprintf(format, args...)})
+}
+
+// loseField — потеря одного поля, после которой правило проверки переводится как есть
+func (c *importCollector) loseField(site, field, format string, args ...interface{}) {
+	c.lossy = append(c.lossy, SiteNote{Site: site, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ImportSites переводит базу другого инструмента в SiteInfo и убирает сайты,
+// которые уже есть в existing (по хосту или имени).
+func ImportSites(format string, data []byte, existing []SiteInfo) (*ImportResult, error) {
+	c, err := convertSites(format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := map[string]string{}
+	names := map[string]bool{}
+	for _, site := range existing {
+		hosts[siteHost(site.BaseURL)] = site.Name
+		names[strings.ToLower(site.Name)] = true
+	}
+
+	result := &ImportResult{Skipped: c.skipped, Lossy: c.lossy}
+	for _, site := range c.sites {
+		host := siteHost(site.BaseURL)
+		if other, dup := hosts[host]; dup {
+			result.Skipped = append(result.Skipped, SiteNote{Site: site.Name, Message: fmt.Sprintf("host %s already covered by %q", host, other)})
+			continue
+		}
+		if names[strings.ToLower(site.Name)] {
+			result.Skipped = append(result.Skipped, SiteNote{Site: site.Name, Message: "name already exists"})
+			continue
+		}
+		if issues := LintSites([]SiteInfo{site}); hasLintErrors(issues) {
+			result.Skipped = append(result.Skipped, SiteNote{Site: site.Name, Message: "invalid after conversion: " + issues[0].Message})
+			continue
+		}
+		hosts[host] = site.Name
+		names[strings.ToLower(site.Name)] = true
+		result.Imported = append(result.Imported, site)
+	}
+	return result, nil
+}
+
+// convertSites переводит правила без проверки на дубликаты
+func convertSites(format string, data []byte) (*importCollector, error) {
+	c := &importCollector{}
+	var err error
+	switch format {
+	case FormatSherlock:
+		err = importSherlock(data, c)
+	case FormatWhatsMyName:
+		err = importWhatsMyName(data, c)
+	case FormatMaigret:
+		err = importMaigret(data, c)
+	default:
+		return nil, fmt.Errorf("unknown import format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", format, err)
+	}
+	return c, nil
+}
+
+func hasLintErrors(issues []SiteIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// siteHost — хост профиля без "www." для поиска дубликатов
+func siteHost(template string) string {
+	u, err := url.Parse(strings.Replace(template, "{}", "username", -1))
+	if err != nil {
+		return template
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// sortedKeys — имена сайтов в стабильном порядке
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// oneOrMany разбирает значение, которое бывает и скаляром, и массивом
+type oneOrMany[T any] []T
+
+func (o *oneOrMany[T]) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		var many []T
+		if err := json.Unmarshal(data, &many); err != nil {
+			return err
+		}
+		*o = many
+		return nil
+	}
+	var one T
+	if err := json.Unmarshal(data, &one); err != nil {
+		return err
+	}
+	*o = []T{one}
+	return nil
+}
+
+// --- Sherlock (sherlock_project/resources/data.json) ---
+
+type sherlockSite struct {
+	URL           string            `json:"url"`
+	URLProbe      string            `json:"urlProbe"`
+	ErrorType     string            `json:"errorType"`
+	ErrorMsg      oneOrMany[string] `json:"errorMsg"`
+	ErrorCode     oneOrMany[int]    `json:"errorCode"`
+	RegexCheck    string            `json:"regexCheck"`
+	RequestMethod string            `json:"request_method"`
+	Headers       map[string]string `json:"headers"`
+	Claimed       string            `json:"username_claimed"`
+}
+
+func importSherlock(data []byte, c *importCollector) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(raw) {
+		if strings.HasPrefix(name, "$") {
+			continue // "$schema"
+		}
+		var s sherlockSite
+		if err := json.Unmarshal(raw[name], &s); err != nil {
+			c.skip(name, "cannot parse entry: %v", err)
+			continue
+		}
+		if s.RequestMethod != "" && !strings.EqualFold(s.RequestMethod, "GET") {
+			c.skip(name, "request_method %s is not supported", s.RequestMethod)
+			continue
+		}
+
+		site := SiteInfo{Name: name, BaseURL: s.URL}
+		if s.Claimed != "" {
+			site.KnownPresent = []string{s.Claimed}
+		}
+		if s.URLProbe != "" && s.URLProbe != s.URL {
+			site.URLProbe = s.URLProbe
+		}
+
+		switch s.ErrorType {
+		case "status_code":
+			site.ErrorType = "status_code"
+			switch len(s.ErrorCode) {
+			case 0:
+				// Sherlock считает "не найден" любой не-2xx ответ; код не угадываем, его подтвердит sites selftest
+				site.ErrorType = "unknown"
+				c.lose(name, "any non-2xx status means not found in Sherlock; imported as errorType unknown until sites selftest confirms errorCode")
+			default:
+				site.ErrorCode = s.ErrorCode[0]
+				if len(s.ErrorCode) > 1 {
+					c.lose(name, "only the first of errorCode %v is kept", []int(s.ErrorCode))
+				}
+			}
+		case "message":
+			if len(s.ErrorMsg) == 0 || s.ErrorMsg[0] == "" {
+				c.skip(name, "errorType message without errorMsg")
+				continue
+			}
+			site.ErrorType = "errorMsg"
+			site.ErrorMsg = s.ErrorMsg[0]
+			if len(s.ErrorMsg) > 1 {
+				c.lose(name, "only the first of %d errorMsg strings is kept", len(s.ErrorMsg))
+			}
+		case "response_url":
+			c.skip(name, "errorType response_url (redirect target check) is not supported")
+			continue
+		default:
+			c.skip(name, "unknown errorType %q", s.ErrorType)
+			continue
+		}
+
+		if s.RegexCheck != "" {
+			c.lose(name, "regexCheck %q is not enforced", s.RegexCheck)
+		}
+		var dropped bool
+		if site.Cookies, dropped = cookiesFromHeaders(s.Headers); dropped {
+			c.lose(name, "custom headers other than Cookie are dropped")
+		}
+		c.sites = append(c.sites, site)
+	}
+	return nil
+}
+
+// --- WhatsMyName (wmn-data.json) ---
+
+type wmnSite struct {
+	Name      string            `json:"name"`
+	URICheck  string            `json:"uri_check"`
+	URIPretty string            `json:"uri_pretty"`
+	PostBody  string            `json:"post_body"`
+	ECode     int               `json:"e_code"`
+	EString   string            `json:"e_string"`
+	MCode     int               `json:"m_code"`
+	MString   string            `json:"m_string"`
+	Valid     *bool             `json:"valid"`
+	Headers   map[string]string `json:"headers"`
+	Known     []string          `json:"known"`
+}
+
+func importWhatsMyName(data []byte, c *importCollector) error {
+	var doc struct {
+		Sites []wmnSite `json:"sites"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	for _, s := range doc.Sites {
+		name := s.Name
+		if s.Valid != nil && !*s.Valid {
+			c.skip(name, "marked as invalid upstream")
+			continue
+		}
+		if s.PostBody != "" {
+			c.skip(name, "POST checks are not supported")
+			continue
+		}
+
+		probe := strings.ReplaceAll(s.URICheck, "{account}", "{}")
+		site := SiteInfo{Name: name, BaseURL: probe, KnownPresent: s.Known}
+		if s.URIPretty != "" {
+			site.BaseURL = strings.ReplaceAll(s.URIPretty, "{account}", "{}")
+			if site.BaseURL != probe {
+				site.URLProbe = probe
+			}
+		}
+
+		// WMN: найден, если статус == e_code и есть e_string; не найден, если статус == m_code и есть m_string.
+		// У нас одно условие, поэтому выбираем самое различающее.
+		switch {
+		case s.ECode != s.MCode && s.MCode >= 100:
+			site.ErrorType = "status_code"
+			site.ErrorCode = s.MCode
+			c.lose(name, "body strings are not checked; only m_code %d is used", s.MCode)
+		case s.EString != "":
+			site.ErrorType = "profilePresence"
+			site.ErrorMsg = s.EString
+			if s.MString != "" {
+				c.lose(name, "m_string is not checked; only e_string is used")
+			}
+		case s.MString != "":
+			site.ErrorType = "errorMsg"
+			site.ErrorMsg = s.MString
+		default:
+			c.skip(name, "no distinguishing status code or string")
+			continue
+		}
+
+		var dropped bool
+		if site.Cookies, dropped = cookiesFromHeaders(s.Headers); dropped {
+			c.lose(name, "custom headers other than Cookie are dropped")
+		}
+		c.sites = append(c.sites, site)
+	}
+	return nil
+}
+
+// --- Maigret (maigret/resources/data.json) ---
+
+type maigretSite struct {
+	URL          string            `json:"url"`
+	URLMain      string            `json:"urlMain"`
+	URLProbe     string            `json:"urlProbe"`
+	URLSubpath   string            `json:"urlSubpath"`
+	CheckType    string            `json:"checkType"`
+	AbsenceStrs  []string          `json:"absenceStrs"`
+	PresenseStrs []string          `json:"presenseStrs"`
+	Engine       string            `json:"engine"`
+	Disabled     bool              `json:"disabled"`
+	RegexCheck   string            `json:"regexCheck"`
+	Headers      map[string]string `json:"headers"`
+	RequestHead  bool              `json:"request_head_only"`
+	Claimed      string            `json:"usernameClaimed"`
+	Unclaimed    string            `json:"usernameUnclaimed"`
+}
+
+func importMaigret(data []byte, c *importCollector) error {
+	var doc struct {
+		Sites   map[string]json.RawMessage `json:"sites"`
+		Engines map[string]struct {
+			Site json.RawMessage `json:"site"`
+		} `json:"engines"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(doc.Sites) {
+		var s maigretSite
+		// Сначала поля движка (шаблона), затем поля сайта поверх них
+		if engine, ok := doc.Engines[peekMaigretEngine(doc.Sites[name])]; ok && len(engine.Site) > 0 {
+			json.Unmarshal(engine.Site, &s)
+		}
+		if err := json.Unmarshal(doc.Sites[name], &s); err != nil {
+			c.skip(name, "cannot parse entry: %v", err)
+			continue
+		}
+		if s.Disabled {
+			c.skip(name, "disabled upstream")
+			continue
+		}
+
+		expand := func(template string) string {
+			template = strings.ReplaceAll(template, "{urlMain}", strings.TrimRight(s.URLMain, "/"))
+			template = strings.ReplaceAll(template, "{urlSubpath}", s.URLSubpath)
+			return strings.ReplaceAll(template, "{username}", "{}")
+		}
+		site := SiteInfo{Name: name, BaseURL: expand(s.URL)}
+		// Имена для sites selftest, в том числе чтобы подтвердить errorCode
+		if s.Claimed != "" {
+			site.KnownPresent = []string{s.Claimed}
+		}
+		if s.Unclaimed != "" {
+			site.KnownAbsent = []string{s.Unclaimed}
+		}
+		if s.URLProbe != "" {
+			site.URLProbe = expand(s.URLProbe)
+		}
+
+		switch s.CheckType {
+		case "status_code":
+			// Maigret считает "не найден" любой не-2xx ответ, а кодов не хранит
+			site.ErrorType = "unknown"
+			c.lose(name, "any non-2xx status means not found in Maigret; imported as errorType unknown until sites selftest confirms errorCode")
+		case "message":
+			switch {
+			case len(s.AbsenceStrs) > 0:
+				site.ErrorType = "errorMsg"
+				site.ErrorMsg = s.AbsenceStrs[0]
+				if len(s.AbsenceStrs) > 1 {
+					c.lose(name, "only the first of %d absenceStrs is kept", len(s.AbsenceStrs))
+				}
+				if len(s.PresenseStrs) > 0 {
+					c.lose(name, "presenseStrs are not checked")
+				}
+			case len(s.PresenseStrs) > 0:
+				site.ErrorType = "profilePresence"
+				site.ErrorMsg = s.PresenseStrs[0]
+				if len(s.PresenseStrs) > 1 {
+					c.lose(name, "only the first of %d presenseStrs is kept", len(s.PresenseStrs))
+				}
+			default:
+				c.skip(name, "checkType message without absenceStrs or presenseStrs")
+				continue
+			}
+		case "response_url":
+			c.skip(name, "checkType response_url (redirect target check) is not supported")
+			continue
+		default:
+			c.skip(name, "unknown checkType %q", s.CheckType)
+			continue
+		}
+
+		if s.RegexCheck != "" {
+			c.lose(name, "regexCheck %q is not enforced", s.RegexCheck)
+		}
+		if len(s.Headers) > 0 {
+			c.lose(name, "custom headers are dropped")
+		}
+		if s.RequestHead {
+			c.lose(name, "HEAD-only request is replaced with GET")
+		}
+		c.sites = append(c.sites, site)
+	}
+	return nil
+}
+
+func peekMaigretEngine(raw json.RawMessage) string {
+	var s struct {
+		Engine string `json:"engine"`
+	}
+	json.Unmarshal(raw, &s)
+	return s.Engine
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportFormats(t *testing.T) {
+	tests := []struct {
+		format   string
+		data     string
+		imported []SiteInfo
+		skipped  []string
+		lossy    []string // по одному имени на каждое замечание
+	}{
+		{
+			format: FormatSherlock,
+			data: `{
+				"$schema": "https://example.com/data.schema.json",
+				"GitHub": {"url": "https://www.github.com/{}", "urlMain": "https://www.github.com/", "errorType": "status_code", "username_claimed": "blue"},
+				"Codes": {"url": "https://codes.example/{}", "errorType": "status_code", "errorCode": [404, 410]},
+				"Gone": {"url": "https://gone.example/{}", "urlProbe": "https://gone.example/{}", "errorType": "status_code", "errorCode": 410},
+				"Forum": {"url": "https://forum.example/u/{}", "urlProbe": "https://forum.example/api/{}", "errorType": "message",
+					"errorMsg": ["Not found", "Banned"], "regexCheck": "^[a-z]+$", "headers": {"Cookie": "a=1; b=2", "X-Api": "k"}},
+				"Post": {"url": "https://post.example/{}", "errorType": "status_code", "errorCode": 404, "request_method": "POST"},
+				"Redirects": {"url": "https://redirects.example/{}", "errorType": "response_url"},
+				"NoMsg": {"url": "https://nomsg.example/{}", "errorType": "message"},
+				"Broken": {"url": 5}
+			}`,
+			imported: []SiteInfo{
+				{Name: "Codes", BaseURL: "https://codes.example/{}", ErrorType: "status_code", ErrorCode: 404},
+				{Name: "Forum", BaseURL: "https://forum.example/u/{}", URLProbe: "https://forum.example/api/{}", ErrorType: "errorMsg", ErrorMsg: "Not found",
+					Cookies: []SiteCookie{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}},
+				// Код "не найден" не угадывается: сайт ждет подтверждения sites selftest
+				{Name: "GitHub", BaseURL: "https://www.github.com/{}", ErrorType: "unknown", KnownPresent: []string{"blue"}},
+				{Name: "Gone", BaseURL: "https://gone.example/{}", ErrorType: "status_code", ErrorCode: 410},
+			},
+			skipped: []string{"Broken", "NoMsg", "Post", "Redirects"},
+			lossy:   []string{"Codes", "Forum", "Forum", "Forum", "GitHub"},
+		},
+		{
+			format: FormatWhatsMyName,
+			data: `{"sites": [
+				{"name": "Status", "uri_check": "https://status.example/api/{account}", "uri_pretty": "https://status.example/{account}",
+					"e_code": 200, "e_string": "profile", "m_code": 404, "m_string": "missing", "known": ["alice"]},
+				{"name": "Presence", "uri_check": "https://presence.example/{account}", "e_code": 200, "e_string": "Joined", "m_code": 200, "m_string": "Sorry"},
+				{"name": "Missing", "uri_check": "https://missing.example/{account}", "e_code": 200, "m_code": 200, "m_string": "Not found"},
+				{"name": "Headers", "uri_check": "https://headers.example/{account}", "e_code": 200, "m_code": 404,
+					"headers": {"Cookie": "c=1", "User-Agent": "x"}},
+				{"name": "Invalid", "uri_check": "https://invalid.example/{account}", "e_code": 200, "m_code": 404, "valid": false},
+				{"name": "Post", "uri_check": "https://post.example/api", "post_body": "{\"user\": \"{account}\"}", "e_code": 200, "m_code": 404},
+				{"name": "Same", "uri_check": "https://same.example/{account}", "e_code": 200, "m_code": 200}
+			]}`,
+			imported: []SiteInfo{
+				{Name: "Status", BaseURL: "https://status.example/{}", URLProbe: "https://status.example/api/{}", ErrorType: "status_code", ErrorCode: 404, KnownPresent: []string{"alice"}},
+				{Name: "Presence", BaseURL: "https://presence.example/{}", ErrorType: "profilePresence", ErrorMsg: "Joined"},
+				{Name: "Missing", BaseURL: "https://missing.example/{}", ErrorType: "errorMsg", ErrorMsg: "Not found"},
+				{Name: "Headers", BaseURL: "https://headers.example/{}", ErrorType: "status_code", ErrorCode: 404, Cookies: []SiteCookie{{Name: "c", Value: "1"}}},
+			},
+			skipped: []string{"Invalid", "Post", "Same"},
+			lossy:   []string{"Status", "Presence", "Headers", "Headers"},
+		},
+		{
+			format: FormatMaigret,
+			data: `{
+				"engines": {"Discourse": {"site": {"url": "{urlMain}/u/{username}", "checkType": "status_code"}}},
+				"sites": {
+					"Forum": {"engine": "Discourse", "urlMain": "https://forum.example/", "usernameClaimed": "alice", "usernameUnclaimed": "noonenothere"},
+					"Blog": {"url": "https://{username}.blog.example", "checkType": "message", "absenceStrs": ["Not found", "Gone"], "presenseStrs": ["Posts"]},
+					"Shop": {"url": "https://shop.example/{username}", "urlProbe": "https://shop.example/api/{username}", "checkType": "message",
+						"presenseStrs": ["Seller"], "headers": {"X-Token": "1"}, "request_head_only": true},
+					"Old": {"url": "https://old.example/{username}", "checkType": "status_code", "disabled": true},
+					"Redir": {"url": "https://redir.example/{username}", "checkType": "response_url"},
+					"Empty": {"url": "https://empty.example/{username}", "checkType": "message"},
+					"Regex": {"url": "https://regex.example/{username}", "checkType": "regex"}
+				}
+			}`,
+			imported: []SiteInfo{
+				{Name: "Blog", BaseURL: "https://{}.blog.example", ErrorType: "errorMsg", ErrorMsg: "Not found"},
+				// Движок дает url и checkType; кода у Maigret нет, поэтому unknown
+				{Name: "Forum", BaseURL: "https://forum.example/u/{}", ErrorType: "unknown", KnownPresent: []string{"alice"}, KnownAbsent: []string{"noonenothere"}},
+				{Name: "Shop", BaseURL: "https://shop.example/{}", URLProbe: "https://shop.example/api/{}", ErrorType: "profilePresence", ErrorMsg: "Seller"},
+			},
+			skipped: []string{"Empty", "Old", "Redir", "Regex"},
+			lossy:   []string{"Blog", "Blog", "Forum", "Shop", "Shop"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			result, err := ImportSites(tt.format, []byte(tt.data), nil)
+			if err != nil {
+				t.Fatalf("ImportSites: %v", err)
+			}
+			if !reflect.DeepEqual(result.Imported, tt.imported) {
+				t.Errorf("imported:\n%+v\nwant:\n%+v", result.Imported, tt.imported)
+			}
+			if got := noteSites(result.Skipped); !equalStrings(got, tt.skipped) {
+				t.Errorf("skipped = %v, want %v", got, tt.skipped)
+			}
+			if got := noteSites(result.Lossy); !equalStrings(got, tt.lossy) {
+				t.Errorf("lossy = %v, want %v", got, tt.lossy)
+			}
+			// Все импортированное проходит проверку базы
+			if err := validateSites(result.Imported); err != nil {
+				t.Errorf("imported sites do not pass lint: %v", err)
+			}
+		})
+	}
+}
+
+func TestImportUnconfirmedCodeIsLossy(t *testing.T) {
+	for format, data := range map[string]string{
+		FormatSherlock: `{"Site": {"url": "https://site.example/{}", "errorType": "status_code"}}`,
+		FormatMaigret:  `{"sites": {"Site": {"url": "https://site.example/{username}", "checkType": "status_code"}}}`,
+	} {
+		result, err := ImportSites(format, []byte(data), nil)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(result.Imported) != 1 || result.Imported[0].ErrorCode != nil || result.Imported[0].ErrorType != "unknown" {
+			t.Errorf("%s: imported %+v, want errorType unknown without errorCode", format, result.Imported)
+		}
+		if len(result.Lossy) != 1 || !strings.Contains(result.Lossy[0].Message, "sites selftest") {
+			t.Errorf("%s: lossy %+v, want a note to confirm the code with sites selftest", format, result.Lossy)
+		}
+	}
+}
+
+func TestImportDedupe(t *testing.T) {
+	existing := []SiteInfo{
+		{Name: "GitHub", BaseURL: "https://github.com/{}", ErrorType: "status_code", ErrorCode: 404},
+		{Name: "Status", BaseURL: "https://status.example/{}", ErrorType: "status_code", ErrorCode: 404},
+	}
+	data := `{
+		"GitHub Mirror": {"url": "https://www.GitHub.com/{}", "errorType": "status_code", "errorCode": 404},
+		"STATUS": {"url": "https://other-status.example/{}", "errorType": "status_code", "errorCode": 404},
+		"New": {"url": "https://new.example/user/{}", "errorType": "status_code", "errorCode": 404},
+		"New Again": {"url": "https://new.example/profile/{}", "errorType": "status_code", "errorCode": 404},
+		"NoPlaceholder": {"url": "https://noplaceholder.example/profile", "errorType": "status_code", "errorCode": 404}
+	}`
+	result, err := ImportSites(FormatSherlock, []byte(data), existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != 1 || result.Imported[0].Name != "New" {
+		t.Errorf("imported %+v, want only New", result.Imported)
+	}
+
+	// Хост без "www." и в любом регистре, имя без учета регистра, повтор хоста внутри файла, невалидный шаблон
+	want := map[string]string{
+		"GitHub Mirror": `host github.com already covered by "GitHub"`,
+		"STATUS":        "name already exists",
+		"New Again":     `host new.example already covered by "New"`,
+		"NoPlaceholder": "invalid after conversion",
+	}
+	if len(result.Skipped) != len(want) {
+		t.Errorf("skipped %+v, want %d sites", result.Skipped, len(want))
+	}
+	for _, note := range result.Skipped {
+		if !strings.Contains(note.Message, want[note.Site]) || want[note.Site] == "" {
+			t.Errorf("%s skipped with %q, want %q", note.Site, note.Message, want[note.Site])
+		}
+	}
+}
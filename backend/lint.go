@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Допустимые значения errorType (см. sites.schema.json)
+var knownErrorTypes = map[string]bool{
+	"status_code":     true,
+	"errorMsg":        true,
+	"profilePresence": true,
+	"unknown":         true,
+}
+
+// Теги свободные, но в одном стиле: "bug-bounty", "link-in-bio"
+var tagPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// SiteIssue — проблема в описании сайта
+type SiteIssue struct {
+	Index    int    `json:"index"` // позиция в базе
+	Site     string `json:"site"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (i SiteIssue) String() string {
+	name := i.Site
+	if name == "" {
+		name = fmt.Sprintf("#%d", i.Index)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, name, i.Message)
+}
+
+// LintSites проверяет базу по правилам sites.schema.json и ищет дубликаты имен.
+// Ошибки делают сайт непроверяемым, предупреждения — о полях, которые будут проигнорированы.
+func LintSites(list []SiteInfo) []SiteIssue {
+	var issues []SiteIssue
+	seen := map[string]int{}
+
+	for i, site := range list {
+		report := func(severity, format string, args ...interface{}) {
+			issues = append(issues, SiteIssue{Index: i, Site: site.Name, Severity: severity, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if strings.TrimSpace(site.Name) == "" {
+			report(SeverityError, "name is empty")
+		} else if first, dup := seen[strings.ToLower(site.Name)]; dup {
+			report(SeverityError, "duplicate name (first defined at #%d)", first)
+		} else {
+			seen[strings.ToLower(site.Name)] = i
+		}
+
+		if site.Input != "" && site.Input != SiteInputUsername && site.Input != SiteInputEmail {
+			report(SeverityError, "input %q: expected username or email", site.Input)
+		}
+		if msg := checkURLTemplate(site.BaseURL, siteInput(site)); msg != "" {
+			report(SeverityError, "base_url %s", msg)
+		}
+		if site.URLProbe != "" {
+			if msg := checkURLTemplate(site.URLProbe, siteInput(site)); msg != "" {
+				report(SeverityError, "url_probe %s", msg)
+			}
+		}
+
+		if site.Popularity < 0 || site.Popularity > 100 {
+			report(SeverityError, "popularity %d is outside 1..100", site.Popularity)
+		}
+		if len(site.Categories) == 0 {
+			report(SeverityWarning, "no categories; the site is skipped by category-filtered searches")
+		}
+		for _, category := range site.Categories {
+			if !knownCategories[category] {
+				report(SeverityError, "unknown category %q", category)
+			}
+		}
+		for _, tag := range site.Tags {
+			if !tagPattern.MatchString(tag) {
+				report(SeverityWarning, "tag %q should be lowercase words joined by '-'", tag)
+			}
+		}
+
+		for _, username := range site.KnownAbsent {
+			if strings.TrimSpace(username) == "" {
+				report(SeverityError, "known_absent has an empty username")
+			}
+		}
+		for _, username := range site.KnownPresent {
+			if strings.TrimSpace(username) == "" {
+				report(SeverityError, "known_present has an empty username")
+			}
+			for _, absent := range site.KnownAbsent {
+				if strings.EqualFold(username, absent) {
+					report(SeverityError, "%q is both known_present and known_absent", username)
+				}
+			}
+		}
+
+		if group, err := parseProxyPolicy(site.Proxy); err != nil {
+			report(SeverityError, "%v", err)
+		} else if group != "" && len(Settings().Proxy.Groups[group]) == 0 {
+			// Иначе каждая проверка сайта закончится ошибкой «group has no proxies»
+			report(SeverityError, "proxy group %q has no proxies in proxy.groups", group)
+		}
+		if _, ok := headerProfiles[site.HeaderProfile]; site.HeaderProfile != "" && !ok {
+			report(SeverityError, "unknown header_profile %q", site.HeaderProfile)
+		}
+
+		if !knownErrorTypes[site.ErrorType] {
+			report(SeverityError, "unknown errorType %q", site.ErrorType)
+			continue
+		}
+
+		switch site.ErrorType {
+		case "status_code":
+			if site.ErrorCode == nil {
+				// Код «нет профиля» не угадываем: пока его не подтвердит sites selftest, у сайта errorType unknown
+				report(SeverityError, "errorType status_code requires errorCode; use errorType unknown until sites selftest confirms the code")
+			} else if code, ok := errorCodeInt(site.ErrorCode); !ok {
+				report(SeverityError, "errorCode %v is not an integer", site.ErrorCode)
+			} else if code < 100 || code > 599 {
+				report(SeverityError, "errorCode %d is not an HTTP status", code)
+			}
+			if site.ErrorMsg != "" {
+				report(SeverityWarning, "errorMsg is ignored for errorType status_code")
+			}
+		case "errorMsg", "profilePresence":
+			if site.ErrorMsg == "" {
+				report(SeverityError, "errorType %s requires a non-empty errorMsg", site.ErrorType)
+			}
+			if site.ErrorCode != nil {
+				report(SeverityWarning, "errorCode is ignored for errorType %s", site.ErrorType)
+			}
+		}
+	}
+
+	// Имена и алиасы должны однозначно указывать на сайт в sites= и exclude_sites=
+	owners := map[string]string{}
+	for _, site := range list {
+		if _, taken := owners[normalizeSiteName(site.Name)]; !taken {
+			owners[normalizeSiteName(site.Name)] = site.Name
+		}
+	}
+	for i, site := range list {
+		for _, alias := range site.Aliases {
+			key := normalizeSiteName(alias)
+			owner, taken := owners[key]
+			switch {
+			case key == "":
+				issues = append(issues, SiteIssue{Index: i, Site: site.Name, Severity: SeverityError, Message: fmt.Sprintf("alias %q is empty", alias)})
+			case taken && owner != site.Name:
+				issues = append(issues, SiteIssue{Index: i, Site: site.Name, Severity: SeverityError, Message: fmt.Sprintf("alias %q already refers to %q", alias, owner)})
+			case taken:
+				issues = append(issues, SiteIssue{Index: i, Site: site.Name, Severity: SeverityWarning, Message: fmt.Sprintf("alias %q matches the name and is redundant", alias)})
+			default:
+				owners[key] = site.Name
+			}
+		}
+	}
+	return issues
+}
+
+// checkURLTemplate возвращает описание проблемы или пустую строку;
+// у email-сайтов вместо {} должен быть {email}, {md5} или {sha256}
+func checkURLTemplate(raw, input string) string {
+	if raw == "" {
+		return "is empty"
+	}
+	u, err := url.Parse(expandSiteURL(raw, SiteInfo{Input: input}, "username@example.com"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Sprintf("%q is not an http(s) URL", raw)
+	}
+	if input != SiteInputEmail {
+		if !strings.Contains(raw, "{}") {
+			return fmt.Sprintf("%q has no {} placeholder", raw)
+		}
+		return ""
+	}
+	for _, placeholder := range emailPlaceholders {
+		if strings.Contains(raw, placeholder) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%q has no %s placeholder", raw, strings.Join(emailPlaceholders, ", "))
+}
+
+// errorCodeInt приводит errorCode (float64 из JSON или int) к int
+func errorCodeInt(v interface{}) (int, bool) {
+	switch code := v.(type) {
+	case float64:
+		if code != math.Trunc(code) {
+			return 0, false
+		}
+		return int(code), true
+	case int:
+		return code, true
+	}
+	return 0, false
+}
+
+// validateSites не пускает в работу базу с ошибками: лучше упасть, чем молча пропускать сайты
+func validateSites(list []SiteInfo) error {
+	var errs []error
+	for _, issue := range LintSites(list) {
+		if issue.Severity == SeverityError {
+			errs = append(errs, errors.New(issue.String()))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// openAPISpec — описание /v1; правится вручную вместе с типами ниже
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// openAPISchemas — какой Go-тип стоит за каждой схемой из components.schemas
+var openAPISchemas = map[string]reflect.Type{
+	"Error":            reflect.TypeOf(APIError{}),
+	"SearchRequest":    reflect.TypeOf(searchBody{}),
+	"StringOrList":     reflect.TypeOf(oneOrMany[string]{}),
+	"SearchResult":     reflect.TypeOf(SearchResult{}),
+	"SiteResult":       reflect.TypeOf(SiteResult{}),
+	"TelegramResult":   reflect.TypeOf(TelegramResult{}),
+	"Breach":           reflect.TypeOf(Breach{}),
+	"BreachRange":      reflect.TypeOf(BreachRange{}),
+	"BreachRangeMatch": reflect.TypeOf(BreachRangeMatch{}),
+	"BatchResponse":    reflect.TypeOf(BatchResponse{}),
+	"BatchLine":        reflect.TypeOf(BatchLine{}),
+	"BatchMatrix":      reflect.TypeOf(BatchMatrix{}),
+	"BatchMatrixRow":   reflect.TypeOf(BatchMatrixRow{}),
+}
+
+// OpenAPISpec возвращает openapi.json, который отдается на /v1/openapi.json
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
+
+// openAPIRequestSchemas — схемы тел запросов: обязательность их полей с Go не сверяем
+var openAPIRequestSchemas = map[string]bool{"SearchRequest": true}
+
+// handleOpenAPI отдает openapi.json; доступен без ключа
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	contentType := negotiatedType(r)
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(openAPISpec)
+}
+
+// openAPISchema — часть схемы OpenAPI, которую сверяем с Go-типами
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref"`
+	Type                 string                    `json:"type"`
+	Enum                 []string                  `json:"enum"`
+	Required             []string                  `json:"required"`
+	Properties           map[string]*openAPISchema `json:"properties"`
+	Items                *openAPISchema            `json:"items"`
+	AdditionalProperties json.RawMessage           `json:"additionalProperties"`
+}
+
+// CheckOpenAPI сверяет openapi.json с кодом: поля, типы и обязательность схем,
+// коды ошибок и эндпоинты /v1. Пустой список — описание актуально.
+func CheckOpenAPI() ([]string, error) {
+	var spec struct {
+		Paths      map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]*openAPISchema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		return nil, fmt.Errorf("openapi.json: %w", err)
+	}
+
+	var problems []string
+	for _, name := range sortedKeys(spec.Components.Schemas) {
+		if _, ok := openAPISchemas[name]; !ok {
+			problems = append(problems, fmt.Sprintf("schema %s: no Go type in openAPISchemas", name))
+		}
+	}
+	for _, name := range sortedKeys(openAPISchemas) {
+		schema, ok := spec.Components.Schemas[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("schema %s: missing from components.schemas", name))
+			continue
+		}
+		problems = append(problems, checkSchemaFields(name, schema, openAPISchemas[name])...)
+	}
+
+	if schema := spec.Components.Schemas["Error"]; schema != nil && schema.Properties["code"] != nil {
+		documented := schema.Properties["code"].Enum
+		for _, code := range apiErrorCodes {
+			if !containsString(documented, code) {
+				problems = append(problems, fmt.Sprintf("schema Error: code %q is not in the enum", code))
+			}
+		}
+		for _, code := range documented {
+			if !containsString(apiErrorCodes, code) {
+				problems = append(problems, fmt.Sprintf("schema Error: code %q is never returned", code))
+			}
+		}
+	}
+
+	for _, route := range v1Routes {
+		path := route.path
+		if strings.HasSuffix(path, "/") {
+			path += "{prefix}"
+		}
+		if _, ok := spec.Paths[path]; !ok {
+			problems = append(problems, fmt.Sprintf("path %s: served under /v1 but not documented", path))
+		}
+	}
+	return problems, nil
+}
+
+// checkSchemaFields сверяет свойства схемы с JSON-полями структуры;
+// поле без omitempty есть в ответе всегда и должно быть в required
+func checkSchemaFields(name string, schema *openAPISchema, t reflect.Type) []string {
+	if t.Kind() != reflect.Struct {
+		return checkSchemaType(name, schema, t)
+	}
+	var problems []string
+	fields := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		jsonName, options, _ := strings.Cut(tag, ",")
+		if !field.IsExported() || jsonName == "-" || jsonName == "" {
+			continue
+		}
+		fields[jsonName] = true
+		where := name + "." + jsonName
+		property, ok := schema.Properties[jsonName]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: missing from the schema", where))
+			continue
+		}
+		required := containsString(schema.Required, jsonName)
+		if omitempty := strings.Contains(options, "omitempty"); omitempty && required {
+			problems = append(problems, fmt.Sprintf("%s: required in the schema but omitempty in Go", where))
+		} else if !omitempty && !required && !openAPIRequestSchemas[name] {
+			problems = append(problems, fmt.Sprintf("%s: always present but not required in the schema", where))
+		}
+		problems = append(problems, checkSchemaType(where, property, field.Type)...)
+	}
+	for _, property := range sortedKeys(schema.Properties) {
+		if !fields[property] {
+			problems = append(problems, fmt.Sprintf("%s.%s: not in the Go type", name, property))
+		}
+	}
+	return problems
+}
+
+// checkSchemaType сверяет type, items и $ref схемы с Go-типом; схемы без type (oneOf) не проверяет
+func checkSchemaType(where string, schema *openAPISchema, t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if schema.Ref != "" {
+		ref := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		if openAPISchemas[ref] != t {
+			return []string{fmt.Sprintf("%s: refers to %s, Go type is %s", where, ref, t)}
+		}
+		return nil
+	}
+	if schema.Type == "" {
+		return nil
+	}
+	if want := openAPIType(t); schema.Type != want {
+		return []string{fmt.Sprintf("%s: type %s in the schema, %s in Go", where, schema.Type, want)}
+	}
+	switch {
+	case schema.Items != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		return checkSchemaType(where+"[]", schema.Items, t.Elem())
+	case len(schema.AdditionalProperties) > 0 && t.Kind() == reflect.Map:
+		var values openAPISchema
+		if json.Unmarshal(schema.AdditionalProperties, &values) == nil {
+			return checkSchemaType(where+"{}", &values, t.Elem())
+		}
+	}
+	return nil
+}
+
+func openAPIType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
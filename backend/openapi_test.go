@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Тот же контроль, что gosearch openapi check: описание /v1 не должно расходиться с кодом
+func TestOpenAPIMatchesCode(t *testing.T) {
+	problems, err := CheckOpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range problems {
+		t.Error(problem)
+	}
+}
+
+func TestOpenAPIServedUnderV1(t *testing.T) {
+	w := httptest.NewRecorder()
+	handleV1(w, httptest.NewRequest("GET", "/v1/openapi.json", nil))
+	if w.Code != 200 || !json.Valid(w.Body.Bytes()) {
+		t.Errorf("GET /v1/openapi.json: %d, valid JSON %v", w.Code, json.Valid(w.Body.Bytes()))
+	}
+}
+
+func TestV1SearchResultHasNoLocalizedText(t *testing.T) {
+	useTestSites(t, statusSite("Nowhere", "https://nowhere.example/{}"))
+	plan := planSearch("alice", SearchOptions{})
+	result := plan.result(nil, nil, breachAnswer{})
+	if result.Error == "" {
+		t.Fatal("unversioned result lost its explanation for people")
+	}
+
+	r := httptest.NewRequest("POST", "/search", nil)
+	if got := apiSearchResult(r, result); got.Error != result.Error {
+		t.Errorf("unversioned: error %q, want %q", got.Error, result.Error)
+	}
+	r = r.WithContext(context.WithValue(r.Context(), apiVersionKey, "v1"))
+	data, _ := json.Marshal(apiSearchResult(r, result))
+	if strings.Contains(string(data), `"error"`) {
+		t.Errorf("/v1 result carries the localized error: %s", data)
+	}
+	if !strings.Contains(string(data), `"found_on":[]`) {
+		t.Errorf("/v1 result must report nothing found through an empty found_on: %s", data)
+	}
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Веса составляющих приоритета; сумма — 1, итоговый балл от 0 до 1
+const (
+	priorityWeightPopularity  = 0.4
+	priorityWeightHitRate     = 0.2
+	priorityWeightReliability = 0.3
+	priorityWeightLatency     = 0.1
+
+	defaultSitePopularity = 30 // для сайтов без popularity в data.json
+	latencySamples        = 32 // сколько последних замеров храним для медианы
+
+	defaultProbeLatency = 2 * time.Second // ожидаемая задержка сайта без замеров
+)
+
+// Исход одной проверки сайта
+type probeOutcome int
+
+const (
+	probeSkipped probeOutcome = iota // сайт не проверялся: errorType unknown или поиск отменен
+	probeHit                         // профиль найден
+	probeMiss                        // профиль не найден, сайт ответил
+	probeError                       // сеть, таймаут, блокировка, нечитаемый ответ
+)
+
+// siteStat — история проверок одного сайта в этом инстансе
+type siteStat struct {
+	probes    int
+	hits      int
+	errors    int
+	latencies []time.Duration // кольцевой буфер
+	next      int
+}
+
+var (
+	siteStatsMu sync.Mutex
+	siteStats   = map[string]*siteStat{}
+)
+
+// recordProbe учитывает результат проверки для ранжирования и автомата отключения сайтов
+func recordProbe(site string, outcome probeOutcome, latency time.Duration) {
+	siteCircuits.record(site, outcome)
+	if outcome == probeSkipped {
+		return
+	}
+
+	siteStatsMu.Lock()
+	defer siteStatsMu.Unlock()
+
+	stat := siteStats[site]
+	if stat == nil {
+		stat = &siteStat{}
+		siteStats[site] = stat
+	}
+	stat.probes++
+	switch outcome {
+	case probeHit:
+		stat.hits++
+	case probeError:
+		stat.errors++
+	}
+	if len(stat.latencies) < latencySamples {
+		stat.latencies = append(stat.latencies, latency)
+	} else {
+		stat.latencies[stat.next] = latency
+		stat.next = (stat.next + 1) % latencySamples
+	}
+}
+
+// SitePriority — балл сайта и из чего он сложился
+type SitePriority struct {
+	Site          string   `json:"site"`
+	Score         float64  `json:"score"`
+	Popularity    int      `json:"popularity"`
+	HitRate       float64  `json:"hit_rate"`
+	Reliability   float64  `json:"reliability"`
+	MedianLatency Duration `json:"median_latency"`
+	Probes        int      `json:"probes"`
+}
+
+// sitePriority считает балл. Доли сглажены (+1/+2), чтобы новый сайт
+// не получал 0 или 1 по первой проверке; без замеров задержка считается средней.
+func sitePriority(site SiteInfo, budget time.Duration) SitePriority {
+	p := SitePriority{Site: site.Name, Popularity: site.Popularity}
+	if p.Popularity == 0 {
+		p.Popularity = defaultSitePopularity
+	}
+
+	siteStatsMu.Lock()
+	stat := siteStats[site.Name]
+	var answered, hits int
+	var median time.Duration
+	if stat != nil {
+		p.Probes = stat.probes
+		answered = stat.probes - stat.errors
+		hits = stat.hits
+		median = medianDuration(stat.latencies)
+	}
+	siteStatsMu.Unlock()
+
+	p.HitRate = float64(hits+1) / float64(answered+2)
+	p.Reliability = float64(answered+1) / float64(p.Probes+2)
+	p.MedianLatency = Duration(median)
+
+	latencyScore := 0.5
+	if median > 0 && budget > 0 {
+		latencyScore = 1 - min(float64(median)/float64(budget), 1)
+	}
+	p.Score = priorityWeightPopularity*float64(p.Popularity)/100 +
+		priorityWeightHitRate*p.HitRate +
+		priorityWeightReliability*p.Reliability +
+		priorityWeightLatency*latencyScore
+	return p
+}
+
+// expectedLatency — сколько обычно длится проверка сайта: медиана замеров или defaultProbeLatency
+func expectedLatency(site string) time.Duration {
+	siteStatsMu.Lock()
+	defer siteStatsMu.Unlock()
+	if stat := siteStats[site]; stat != nil && len(stat.latencies) > 0 {
+		return medianDuration(stat.latencies)
+	}
+	return defaultProbeLatency
+}
+
+func medianDuration(samples []time.Duration) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// skipRuleUnconfirmed — причина пропуска явно выбранного сайта, правило которого не подтверждено (SearchResult.Skipped)
+const skipRuleUnconfirmed = "rule_unconfirmed"
+
+// checkable — сайты, которые checkSite умеет проверять
+func checkable(site SiteInfo) bool {
+	if site.ErrorType == "status_code" && site.ErrorCode == nil {
+		return false // код «нет профиля» еще не подтвержден
+	}
+	return site.ErrorType != "unknown" && knownErrorTypes[site.ErrorType]
+}
+
+// scheduleProbes выбирает сайты для проверки: самые ценные первыми, не больше limit
+// (0 — без ограничения). Сайты, которые обычно не успевают за budget, уходят в конец очереди.
+func scheduleProbes(sites []SiteInfo, limit int, budget time.Duration) []SiteInfo {
+	type ranked struct {
+		site     SiteInfo
+		priority SitePriority
+	}
+	var fits, slow []ranked
+	for _, site := range sites {
+		if !checkable(site) {
+			continue
+		}
+		r := ranked{site, sitePriority(site, budget)}
+		if budget > 0 && time.Duration(r.priority.MedianLatency) > budget {
+			slow = append(slow, r)
+		} else {
+			fits = append(fits, r)
+		}
+	}
+	byScore := func(list []ranked) {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].priority.Score > list[j].priority.Score })
+	}
+	byScore(fits)
+	byScore(slow)
+
+	scheduled := make([]SiteInfo, 0, len(fits)+len(slow))
+	for _, r := range append(fits, slow...) {
+		if limit > 0 && len(scheduled) >= limit {
+			break
+		}
+		scheduled = append(scheduled, r.site)
+	}
+	return scheduled
+}
+
+// handleAdminPriority — текущий рейтинг сайтов по убыванию балла
+func handleAdminPriority(w http.ResponseWriter, r *http.Request) {
+	budget := time.Duration(Settings().Search.SiteTimeout)
+	snapshot := loadSites()
+	list := make([]SitePriority, 0, len(snapshot.Sites))
+	for _, site := range scheduleProbes(snapshot.Sites, 0, budget) {
+		list = append(list, sitePriority(site, budget))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sites_version": snapshot.Version,
+		"budget":        Duration(budget),
+		"sites":         list,
+	})
+}
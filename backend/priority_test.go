@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+// useLatency записывает сайту замеры задержки до конца теста
+func useLatency(t *testing.T, site string, latency time.Duration) {
+	t.Helper()
+	siteStatsMu.Lock()
+	siteStats[site] = &siteStat{probes: 1, latencies: []time.Duration{latency}}
+	siteStatsMu.Unlock()
+	t.Cleanup(func() {
+		siteStatsMu.Lock()
+		delete(siteStats, site)
+		siteStatsMu.Unlock()
+	})
+}
+
+func plannedSites(plan *searchPlan) []string {
+	var sites []string
+	for _, p := range plan.probes {
+		if len(sites) == 0 || sites[len(sites)-1] != p.site.Name {
+			sites = append(sites, p.site.Name)
+		}
+	}
+	return sites
+}
+
+func TestPlanSearchFillsProbeBudget(t *testing.T) {
+	site := func(name string, popularity int) SiteInfo {
+		s := statusSite(name, "https://"+name+".example/{}")
+		s.Popularity = popularity
+		return s
+	}
+	useTestSites(t, site("popular", 90), site("slow", 80), site("fast", 50), site("unknown", 40), site("rare", 10))
+	useLatency(t, "popular", 3*time.Second)
+	useLatency(t, "slow", 5*time.Second)
+	useLatency(t, "fast", 500*time.Millisecond)
+	useLatency(t, "rare", 500*time.Millisecond)
+
+	cfg := Settings()
+	saved := cfg.Search
+	t.Cleanup(func() { cfg.Search = saved })
+	cfg.Search.MaxSites = 100
+
+	tests := []struct {
+		name   string
+		budget time.Duration
+		opts   SearchOptions
+		want   []string
+	}{
+		// 3s + 0.5s + 2s (без замеров) + 0.5s; slow не помещается, но следующие за ним — да
+		{"fill", 6 * time.Second, SearchOptions{}, []string{"popular", "fast", "unknown", "rare"}},
+		{"everything", time.Minute, SearchOptions{}, []string{"popular", "slow", "fast", "unknown", "rare"}},
+		{"only cheap", time.Second, SearchOptions{}, []string{"fast", "rare"}},
+		{"explicit sites ignore the budget", time.Second, SearchOptions{Sites: []string{"slow", "popular"}}, []string{"popular", "slow"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Search.ProbeBudget = Duration(tt.budget)
+			got := plannedSites(planSearch("alice", tt.opts))
+			if !equalStrings(got, tt.want) {
+				t.Errorf("planned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanSearchChargesEveryAccount(t *testing.T) {
+	useTestSites(t, statusSite("Accounts", "https://accounts.example/{}"))
+	useLatency(t, "Accounts", time.Second)
+
+	cfg := Settings()
+	saved := cfg.Search
+	t.Cleanup(func() { cfg.Search = saved })
+
+	// a.b+tag@x.example дает три имени-кандидата: три проверки по секунде
+	opts := SearchOptions{Type: SearchTypeEmail}
+	cfg.Search.ProbeBudget = Duration(2 * time.Second)
+	if plan := planSearch("a.b+tag@x.example", opts); len(plan.probes) != 0 {
+		t.Errorf("2s budget: planned %d probes, want 0", len(plan.probes))
+	}
+	cfg.Search.ProbeBudget = Duration(3 * time.Second)
+	if plan := planSearch("a.b+tag@x.example", opts); len(plan.probes) != 3 {
+		t.Errorf("3s budget: planned %d probes, want 3", len(plan.probes))
+	}
+}
+
+func TestPlanSearchReportsUnconfirmedSites(t *testing.T) {
+	unconfirmed := SiteInfo{Name: "Unconfirmed", BaseURL: "https://unconfirmed.example/{}", ErrorType: "unknown"}
+	useTestSites(t, statusSite("Checked", "https://checked.example/{}"), unconfirmed)
+
+	plan := planSearch("alice", SearchOptions{Sites: []string{"Checked", "Unconfirmed"}})
+	if got := plannedSites(plan); !equalStrings(got, []string{"Checked"}) {
+		t.Errorf("planned %v, want only Checked", got)
+	}
+	if plan.skipped["Unconfirmed"] != skipRuleUnconfirmed {
+		t.Errorf("skipped %v, want Unconfirmed as %s", plan.skipped, skipRuleUnconfirmed)
+	}
+
+	// Без sites= непроверяемые сайты просто не входят в поиск
+	if plan := planSearch("alice", SearchOptions{}); len(plan.skipped) != 0 {
+		t.Errorf("skipped %v without sites=, want none", plan.skipped)
+	}
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Политики выхода в сеть для сайта (поле proxy в data.json)
+const (
+	ProxyPolicyDirect = "direct"       // с IP сервера
+	ProxyPolicyProxy  = "proxy"        // через группу default
+	proxyGroupPrefix  = "proxy_group:" // proxy_group:NAME — через группу NAME
+
+	defaultProxyGroup = "default"
+)
+
+// Ротация прокси внутри группы
+const (
+	ProxyRotationRoundRobin = "round_robin"
+	ProxyRotationRandom     = "random"
+)
+
+// ProxyURL — адрес прокси; в логах и админке пароль скрыт
+type ProxyURL string
+
+func (p ProxyURL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redactProxyURL(string(p)))
+}
+
+func redactProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid"
+	}
+	return u.Redacted()
+}
+
+// parseProxyURL принимает http, https и socks5 (его net/http поддерживает сам)
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", redactProxyURL(raw), err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("%s: scheme must be http, https or socks5", u.Redacted())
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%s: host is missing", u.Redacted())
+	}
+	return u, nil
+}
+
+// parseProxyPolicy возвращает группу прокси или "" для прямого выхода
+func parseProxyPolicy(policy string) (group string, err error) {
+	switch {
+	case policy == "" || policy == ProxyPolicyDirect:
+		return "", nil
+	case policy == ProxyPolicyProxy:
+		return defaultProxyGroup, nil
+	case strings.HasPrefix(policy, proxyGroupPrefix) && len(policy) > len(proxyGroupPrefix):
+		return strings.TrimPrefix(policy, proxyGroupPrefix), nil
+	}
+	return "", fmt.Errorf("proxy policy %q: expected direct, proxy or proxy_group:NAME", policy)
+}
+
+// withProxyPolicy прикрепляет к запросу политику сайта для ProbeTransport
+func withProxyPolicy(ctx context.Context, policy string) context.Context {
+	return context.WithValue(ctx, proxyPolicyKey, policy)
+}
+
+// proxyEntry — один прокси со своим пулом соединений и здоровьем
+type proxyEntry struct {
+	url       *url.URL
+	transport *http.Transport
+	failures  int // неудач подряд
+	downUntil time.Time
+	requests  int
+	errors    int
+}
+
+type proxyGroup struct {
+	entries []*proxyEntry
+	next    int
+}
+
+// proxyRouter — транспорт проверок: выбирает прямой выход или прокси по политике сайта
+type proxyRouter struct {
+	mu            sync.Mutex
+	direct        http.RoundTripper
+	groups        map[string]*proxyGroup
+	rotation      string
+	defaultPolicy string
+	maxFailures   int
+	cooldown      time.Duration
+	rand          *rand.Rand
+	now           func() time.Time
+}
+
+var (
+	probeRouterOnce sync.Once
+	probeRouter     *proxyRouter
+)
+
+// ProbeTransport — транспорт для запросов к сайтам с учетом proxy-политик
+func ProbeTransport() http.RoundTripper {
+	probeRouterOnce.Do(func() {
+		probeRouter = newProxyRouter(Settings().Proxy, http.DefaultTransport)
+	})
+	return probeRouter
+}
+
+// newProxyRouter строит пулы по конфигурации; адреса уже проверены в Config.Validate
+func newProxyRouter(cfg ProxyConfig, direct http.RoundTripper) *proxyRouter {
+	router := &proxyRouter{
+		direct:        direct,
+		groups:        map[string]*proxyGroup{},
+		rotation:      cfg.Rotation,
+		defaultPolicy: cfg.DefaultPolicy,
+		maxFailures:   cfg.MaxFailures,
+		cooldown:      time.Duration(cfg.Cooldown),
+		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:           time.Now,
+	}
+	for name, urls := range cfg.Groups {
+		group := &proxyGroup{}
+		for _, raw := range urls {
+			u, err := parseProxyURL(string(raw))
+			if err != nil {
+				continue
+			}
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.Proxy = http.ProxyURL(u)
+			group.entries = append(group.entries, &proxyEntry{url: u, transport: transport})
+		}
+		router.groups[name] = group
+	}
+	return router
+}
+
+func (p *proxyRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy, _ := req.Context().Value(proxyPolicyKey).(string)
+	if policy == "" {
+		policy = p.defaultPolicy
+	}
+	group, err := parseProxyPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	if group == "" {
+		return p.direct.RoundTrip(req)
+	}
+
+	entry, err := p.pick(group)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := entry.transport.RoundTrip(req)
+	if req.Context().Err() != nil {
+		// Запрос отменил сам поиск — прокси тут ни при чем
+		return resp, err
+	}
+	// Блокировка через прокси — скорее всего забанен его адрес
+	p.report(entry, err == nil && !blockedStatuses[resp.StatusCode])
+	return resp, err
+}
+
+// pick выбирает здоровый прокси группы по правилу ротации
+func (p *proxyRouter) pick(name string) (*proxyEntry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	group := p.groups[name]
+	if group == nil || len(group.entries) == 0 {
+		return nil, fmt.Errorf("proxy group %q has no proxies", name)
+	}
+	now := p.now()
+	var healthy []*proxyEntry
+	for _, entry := range group.entries {
+		if !now.Before(entry.downUntil) {
+			healthy = append(healthy, entry)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil, fmt.Errorf("proxy group %q: all proxies are down", name)
+	}
+
+	var entry *proxyEntry
+	if p.rotation == ProxyRotationRandom {
+		entry = healthy[p.rand.Intn(len(healthy))]
+	} else {
+		entry = healthy[group.next%len(healthy)]
+		group.next++
+	}
+	entry.requests++
+	return entry, nil
+}
+
+// report учитывает исход запроса: max_failures неудач подряд выводят прокси из ротации на cooldown
+func (p *proxyRouter) report(entry *proxyEntry, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ok {
+		entry.failures = 0
+		return
+	}
+	entry.errors++
+	entry.failures++
+	if p.maxFailures > 0 && entry.failures >= p.maxFailures {
+		entry.downUntil = p.now().Add(p.cooldown)
+		entry.failures = 0
+	}
+}
+
+// ProxyStatus — здоровье прокси для админки
+type ProxyStatus struct {
+	Group     string     `json:"group"`
+	URL       string     `json:"url"`
+	Healthy   bool       `json:"healthy"`
+	DownUntil *time.Time `json:"down_until,omitempty"`
+	Requests  int        `json:"requests"`
+	Errors    int        `json:"errors"`
+}
+
+func (p *proxyRouter) status() []ProxyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	list := []ProxyStatus{}
+	now := p.now()
+	for _, name := range sortedKeys(p.groups) {
+		for _, entry := range p.groups[name].entries {
+			s := ProxyStatus{
+				Group:    name,
+				URL:      entry.url.Redacted(),
+				Healthy:  !now.Before(entry.downUntil),
+				Requests: entry.requests,
+				Errors:   entry.errors,
+			}
+			if !s.Healthy {
+				downUntil := entry.downUntil.UTC()
+				s.DownUntil = &downUntil
+			}
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// handleAdminProxies — прокси по группам и их здоровье
+func handleAdminProxies(w http.ResponseWriter, r *http.Request) {
+	router := ProbeTransport().(*proxyRouter)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rotation":       router.rotation,
+		"default_policy": router.defaultPolicy,
+		"proxies":        router.status(),
+	})
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testProxy — HTTP-прокси, который сам отвечает на запрос статусом status
+// и подписывает ответ своим именем
+type testProxy struct {
+	name   string
+	status atomic.Int32
+	server *httptest.Server
+}
+
+func newTestProxy(t *testing.T, name string, status int) *testProxy {
+	t.Helper()
+	p := &testProxy{name: name}
+	p.status.Store(int32(status))
+	p.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.IsAbs() {
+			t.Errorf("proxy %s got a direct request for %s", name, r.URL)
+		}
+		w.Header().Set("X-Test-Proxy", name)
+		w.WriteHeader(int(p.status.Load()))
+	}))
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// testRouter — маршрутизатор с группой pool из proxies и управляемыми часами
+func testRouter(rotation string, maxFailures int, proxies ...*testProxy) (*proxyRouter, *time.Time) {
+	cfg := ProxyConfig{Rotation: rotation, DefaultPolicy: ProxyPolicyDirect, MaxFailures: maxFailures, Cooldown: Duration(time.Minute)}
+	cfg.Groups = map[string][]ProxyURL{}
+	for _, p := range proxies {
+		cfg.Groups["pool"] = append(cfg.Groups["pool"], ProxyURL(p.server.URL))
+	}
+	router := newProxyRouter(cfg, http.DefaultTransport)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	router.now = func() time.Time { return now }
+	return router, &now
+}
+
+// viaPool отправляет запрос через группу pool и возвращает имя прокси, который ответил
+func viaPool(t *testing.T, router *proxyRouter) string {
+	t.Helper()
+	ctx := withProxyPolicy(context.Background(), proxyGroupPrefix+"pool")
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://site.example/alice", nil)
+	resp, err := router.RoundTrip(req)
+	if err != nil {
+		if strings.Contains(err.Error(), "all proxies are down") {
+			return ""
+		}
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	resp.Body.Close()
+	return resp.Header.Get("X-Test-Proxy")
+}
+
+func proxyHealth(router *proxyRouter) map[string]ProxyStatus {
+	health := map[string]ProxyStatus{}
+	for _, s := range router.status() {
+		health[s.URL] = s
+	}
+	return health
+}
+
+func TestProxyRoundRobin(t *testing.T) {
+	a, b := newTestProxy(t, "a", http.StatusOK), newTestProxy(t, "b", http.StatusOK)
+	router, _ := testRouter(ProxyRotationRoundRobin, 3, a, b)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, viaPool(t, router))
+	}
+	if want := []string{"a", "b", "a", "b"}; !equalStrings(got, want) {
+		t.Errorf("rotation %v, want %v", got, want)
+	}
+}
+
+func TestProxyCooldownAndRecovery(t *testing.T) {
+	bad, good := newTestProxy(t, "bad", http.StatusForbidden), newTestProxy(t, "good", http.StatusOK)
+	router, now := testRouter(ProxyRotationRoundRobin, 2, bad, good)
+
+	// Две блокировки подряд выводят bad из ротации
+	for i := 0; i < 4; i++ {
+		viaPool(t, router)
+	}
+	if health := proxyHealth(router)[bad.server.URL]; health.Healthy || health.DownUntil == nil || health.Errors != 2 {
+		t.Fatalf("bad proxy after 2 blocks: %+v, want it down with 2 errors", health)
+	}
+	for i := 0; i < 3; i++ {
+		if got := viaPool(t, router); got != "good" {
+			t.Fatalf("request during cooldown went through %q, want good", got)
+		}
+	}
+
+	// После cooldown прокси снова в ротации; удачный ответ сбрасывает счетчик неудач
+	*now = now.Add(time.Minute)
+	bad.status.Store(http.StatusOK)
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		seen[viaPool(t, router)] = true
+	}
+	if !seen["bad"] || !seen["good"] {
+		t.Errorf("after cooldown requests went through %v, want both proxies", seen)
+	}
+	if health := proxyHealth(router)[bad.server.URL]; !health.Healthy {
+		t.Errorf("bad proxy after recovery: %+v, want healthy", health)
+	}
+}
+
+func TestProxyAllDown(t *testing.T) {
+	bad := newTestProxy(t, "bad", http.StatusTooManyRequests)
+	router, now := testRouter(ProxyRotationRandom, 1, bad)
+
+	if got := viaPool(t, router); got != "bad" {
+		t.Fatalf("first request went through %q, want bad", got)
+	}
+	if got := viaPool(t, router); got != "" {
+		t.Errorf("request with every proxy down went through %q", got)
+	}
+	*now = now.Add(time.Minute)
+	if got := viaPool(t, router); got != "bad" {
+		t.Errorf("request after cooldown went through %q, want bad", got)
+	}
+}
+
+func TestProxyIgnoresCancelledRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(withProxyPolicy(context.Background(), proxyGroupPrefix+"pool"))
+	slow := &testProxy{name: "slow"}
+	slow.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Поиск отменяется, пока прокси еще думает
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer slow.server.Close()
+	router, _ := testRouter(ProxyRotationRoundRobin, 1, slow)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://site.example/alice", nil)
+	if _, err := router.RoundTrip(req); err == nil {
+		t.Fatal("cancelled request succeeded")
+	}
+	if health := proxyHealth(router)[slow.server.URL]; !health.Healthy || health.Errors != 0 {
+		t.Errorf("proxy after a cancelled request: %+v, want healthy without errors", health)
+	}
+}
+
+func TestLintProxyGroup(t *testing.T) {
+	cfg := Settings()
+	saved := cfg.Proxy.Groups
+	cfg.Proxy.Groups = map[string][]ProxyURL{"residential": {"http://proxy.example:8080"}}
+	t.Cleanup(func() { cfg.Proxy.Groups = saved })
+
+	tests := []struct {
+		policy string
+		want   string // подстрока ошибки; пусто — без ошибок
+	}{
+		{"", ""},
+		{ProxyPolicyDirect, ""},
+		{"proxy_group:residential", ""},
+		{"proxy_group:mobile", `proxy group "mobile" has no proxies`},
+		{ProxyPolicyProxy, `proxy group "default" has no proxies`},
+		{"tor", "expected direct, proxy or proxy_group:NAME"},
+	}
+	for _, tt := range tests {
+		site := statusSite("Proxied", "https://proxied.example/{}")
+		site.Proxy = tt.policy
+		var errs []string
+		for _, issue := range LintSites([]SiteInfo{site}) {
+			if issue.Severity == SeverityError {
+				errs = append(errs, issue.Message)
+			}
+		}
+		switch {
+		case tt.want == "" && len(errs) > 0:
+			t.Errorf("proxy %q: unexpected errors %v", tt.policy, errs)
+		case tt.want != "" && (len(errs) != 1 || !strings.Contains(errs[0], tt.want)):
+			t.Errorf("proxy %q: errors %v, want one with %q", tt.policy, errs, tt.want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuotaTier — лимиты одного уровня доступа
+type QuotaTier struct {
+	Daily       int      `json:"daily"`        // поисков в сутки (UTC); 0 — без лимита
+	Burst       int      `json:"burst"`        // поисков подряд; 0 — без лимита
+	BurstWindow Duration `json:"burst_window"` // за сколько восстанавливается весь burst (и batch_burst)
+	BatchBurst  int      `json:"batch_burst"`  // пакетов /search/batch подряд, независимо от размера; 0 — без лимита
+}
+
+var defaultQuotaTier = QuotaTier{Daily: 50, Burst: 5, BurstWindow: Duration(time.Minute), BatchBurst: 1}
+
+// tierFor возвращает уровень для ключа квоты ("tg:<id>" ищется в Users)
+func (c *QuotaConfig) tierFor(key string) (string, QuotaTier) {
+	if id, ok := strings.CutPrefix(key, "tg:"); ok {
+		if name, ok := c.Users[id]; ok {
+			if tier, ok := c.Tiers[name]; ok {
+				return name, tier
+			}
+		}
+	}
+	return "default", c.Default
+}
+
+// quotaCounter — состояние одного ключа: счетчик за сутки и token bucket для burst.
+// У пакетов свое ведро: пакет из сотни имен не должен упираться в burst одиночных поисков.
+type quotaCounter struct {
+	day    string
+	used   int
+	tokens float64
+	last   time.Time
+	batch  tokenBucket
+}
+
+// tokenBucket — ведро на size токенов, которое наполняется целиком за window
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take списывает need токенов; если их не хватает, возвращает, сколько ждать
+func (b *tokenBucket) take(size int, window time.Duration, need float64, now time.Time) (time.Duration, bool) {
+	rate := float64(size) / window.Seconds() // токенов в секунду
+	b.tokens = math.Min(float64(size), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < need {
+		return time.Duration((need - b.tokens) / rate * float64(time.Second)), false
+	}
+	b.tokens -= need
+	return 0, true
+}
+
+// quotaLimiter хранит счетчики в памяти процесса (на Vercel — в пределах инстанса)
+type quotaLimiter struct {
+	mu       sync.Mutex
+	counters map[string]*quotaCounter
+}
+
+// quotaExceeded описывает, какой лимит сработал и когда можно повторить
+type quotaExceeded struct {
+	Limit   string // "daily", "burst" или "batch_burst"
+	Tier    string
+	RetryAt time.Time
+}
+
+var quotaLimits = &quotaLimiter{counters: make(map[string]*quotaCounter)}
+
+// Сколько ключей держим, прежде чем чистить устаревшие
+const quotaPruneThreshold = 10000
+
+// allow списывает searches поисков с дневного лимита ключа и столько же токенов burst; nil — поиск разрешен
+func (l *quotaLimiter) allow(key, tierName string, tier QuotaTier, searches int, now time.Time) *quotaExceeded {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.counter(key, tier, now)
+	if exceeded := c.checkDaily(tierName, tier, searches, now); exceeded != nil {
+		return exceeded
+	}
+	if tier.Burst > 0 && tier.BurstWindow > 0 {
+		bucket := tokenBucket{c.tokens, c.last}
+		wait, ok := bucket.take(tier.Burst, time.Duration(tier.BurstWindow), float64(searches), now)
+		c.tokens, c.last = bucket.tokens, bucket.last
+		if !ok {
+			return &quotaExceeded{Limit: "burst", Tier: tierName, RetryAt: now.Add(wait)}
+		}
+	}
+	c.used += searches
+	return nil
+}
+
+// allowBatch списывает пакет из names имен: каждое имя — с дневного лимита, а сам пакет —
+// один токен batch_burst. Burst одиночных поисков пакет не трогает.
+func (l *quotaLimiter) allowBatch(key, tierName string, tier QuotaTier, names int, now time.Time) *quotaExceeded {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.counter(key, tier, now)
+	if exceeded := c.checkDaily(tierName, tier, names, now); exceeded != nil {
+		return exceeded
+	}
+	if tier.BatchBurst > 0 && tier.BurstWindow > 0 {
+		if wait, ok := c.batch.take(tier.BatchBurst, time.Duration(tier.BurstWindow), 1, now); !ok {
+			return &quotaExceeded{Limit: "batch_burst", Tier: tierName, RetryAt: now.Add(wait)}
+		}
+	}
+	c.used += names
+	return nil
+}
+
+// counter возвращает счетчик ключа на сегодня, заводя новый с полными ведрами
+func (l *quotaLimiter) counter(key string, tier QuotaTier, now time.Time) *quotaCounter {
+	today := now.UTC().Format("2006-01-02")
+	if len(l.counters) > quotaPruneThreshold {
+		for k, c := range l.counters {
+			if c.day != today {
+				delete(l.counters, k)
+			}
+		}
+	}
+
+	c, ok := l.counters[key]
+	if !ok || c.day != today {
+		c = &quotaCounter{day: today, tokens: float64(tier.Burst), last: now,
+			batch: tokenBucket{tokens: float64(tier.BatchBurst), last: now}}
+		l.counters[key] = c
+	}
+	return c
+}
+
+// checkDaily — хватит ли дневного лимита еще на searches поисков
+func (c *quotaCounter) checkDaily(tierName string, tier QuotaTier, searches int, now time.Time) *quotaExceeded {
+	if tier.Daily > 0 && c.used+searches > tier.Daily {
+		y, m, d := now.UTC().Date()
+		return &quotaExceeded{Limit: "daily", Tier: tierName, RetryAt: time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)}
+	}
+	return nil
+}
+
+// quotaKey — API-ключ, пользователь Telegram из initData, иначе IP клиента
+func quotaKey(r *http.Request) string {
+	if key, ok := APIKeyFromContext(r.Context()); ok {
+		return "key:" + key.ID
+	}
+	if user, ok := TelegramUserFromContext(r.Context()); ok {
+		return fmt.Sprintf("tg:%d", user.ID)
+	}
+	return "ip:" + clientIP(r)
+}
+
+// quotaTierHint — уровень API-ключа запроса, если он задан
+func quotaTierHint(r *http.Request) string {
+	if apiKey, ok := APIKeyFromContext(r.Context()); ok {
+		return apiKey.Tier
+	}
+	return ""
+}
+
+// Значения quota.trusted_proxy
+const (
+	TrustedProxyVercel    = "vercel"    // X-Vercel-Forwarded-For и X-Real-IP от Vercel
+	TrustedProxyForwarded = "forwarded" // последняя запись X-Forwarded-For от прокси перед сервисом (Render, nginx)
+)
+
+// clientIP — адрес клиента по заголовкам прокси из quota.trusted_proxy, иначе RemoteAddr.
+// Без прокси перед сервисом заголовки присылает сам клиент, и каждый новый заголовок
+// давал бы новую квоту, поэтому по умолчанию им не верим.
+func clientIP(r *http.Request) string {
+	switch Settings().Quota.TrustedProxy {
+	case TrustedProxyVercel:
+		// Vercel перезаписывает эти заголовки сам, присланные клиентом не доходят
+		for _, header := range []string{"X-Vercel-Forwarded-For", "X-Real-IP"} {
+			if ip := strings.TrimSpace(r.Header.Get(header)); ip != "" {
+				return ip
+			}
+		}
+	case TrustedProxyForwarded:
+		// Последнюю запись X-Forwarded-For дописал ближайший прокси; левые присылает клиент
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			if last := strings.TrimSpace(entries[len(entries)-1]); last != "" {
+				return last
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// checkQuota списывает поиск с ключа; tierHint (уровень API-ключа) важнее назначений quota.users
+func checkQuota(key, tierHint string) *quotaExceeded {
+	tierName, tier := quotaTier(key, tierHint)
+	return quotaLimits.allow(key, tierName, tier, 1, time.Now())
+}
+
+// checkBatchQuota списывает пакет /search/batch из names имен
+func checkBatchQuota(key, tierHint string, names int) *quotaExceeded {
+	tierName, tier := quotaTier(key, tierHint)
+	return quotaLimits.allowBatch(key, tierName, tier, names, time.Now())
+}
+
+// quotaTier — уровень ключа с учетом уровня API-ключа
+func quotaTier(key, tierHint string) (string, QuotaTier) {
+	quotas := &Settings().Quota
+	if hinted, ok := quotas.Tiers[tierHint]; ok {
+		return tierHint, hinted
+	}
+	return quotas.tierFor(key)
+}
+
+// enforceQuota отвечает 429 со временем повтора, если ключ исчерпал лимит
+func enforceQuota(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, tierHint := quotaKey(r), quotaTierHint(r)
+		exceeded := checkQuota(key, tierHint)
+		if exceeded == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeQuotaExceeded(w, r, key, exceeded)
+	})
+}
+
+// writeQuotaExceeded отвечает 429 с Retry-After
+func writeQuotaExceeded(w http.ResponseWriter, r *http.Request, key string, exceeded *quotaExceeded) {
+	retryAfter := int(math.Ceil(time.Until(exceeded.RetryAt).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	log.Printf("Quota exceeded for %s (%s, tier %s)", key, exceeded.Limit, exceeded.Tier)
+
+	details := map[string]interface{}{
+		"limit":       exceeded.Limit,
+		"tier":        exceeded.Tier,
+		"retry_after": retryAfter,
+		"retry_at":    exceeded.RetryAt.UTC().Format(time.RFC3339),
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	if isV1(r) {
+		writeAPIError(w, http.StatusTooManyRequests, codeQuotaExceeded, "Search quota exceeded, retry later", details)
+		return
+	}
+	details["error"] = "Превышен лимит запросов. Повторите позже."
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(details)
+}
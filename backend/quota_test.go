@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientIP(t *testing.T) {
+	vercel := map[string][]string{"X-Vercel-Forwarded-For": {"203.0.113.5"}, "X-Real-IP": {"203.0.113.6"}, "X-Forwarded-For": {"10.0.0.1"}}
+	tests := []struct {
+		name    string
+		trusted string // quota.trusted_proxy
+		headers map[string][]string
+		want    string
+	}{
+		{"remote addr", "", nil, "192.0.2.1"},
+		{"headers without a trusted proxy", "", vercel, "192.0.2.1"},
+		{"vercel header", TrustedProxyVercel, vercel, "203.0.113.5"},
+		{"real ip", TrustedProxyVercel, map[string][]string{"X-Real-IP": {"203.0.113.6"}}, "203.0.113.6"},
+		{"vercel ignores forwarded", TrustedProxyVercel, map[string][]string{"X-Forwarded-For": {"203.0.113.7"}}, "192.0.2.1"},
+		{"rightmost forwarded", TrustedProxyForwarded, map[string][]string{"X-Forwarded-For": {"1.1.1.1, 203.0.113.7"}}, "203.0.113.7"},
+		{"last forwarded header", TrustedProxyForwarded, map[string][]string{"X-Forwarded-For": {"1.1.1.1", "2.2.2.2, 203.0.113.8"}}, "203.0.113.8"},
+		{"forwarded ignores vercel headers", TrustedProxyForwarded, map[string][]string{"X-Real-IP": {"203.0.113.6"}}, "192.0.2.1"},
+	}
+	cfg := Settings()
+	saved := cfg.Quota.TrustedProxy
+	t.Cleanup(func() { cfg.Quota.TrustedProxy = saved })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Quota.TrustedProxy = tt.trusted
+			r := httptest.NewRequest("GET", "/search", nil)
+			for key, values := range tt.headers {
+				r.Header[http.CanonicalHeaderKey(key)] = values
+			}
+			if got := clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInlineQueryChargesQuota(t *testing.T) {
+	profiles := httptest.NewServer(http.NotFoundHandler())
+	defer profiles.Close()
+	useTestSites(t, statusSite("InlineSite", profiles.URL+"/{}"))
+
+	cfg := Settings()
+	tier := cfg.Quota.Default
+	cfg.Quota.Default = QuotaTier{Daily: 1}
+	t.Cleanup(func() { cfg.Quota.Default = tier })
+
+	bot := &fakeBotAPI{sent: make(chan botCall, 1)}
+	api := httptest.NewServer(bot)
+	defer api.Close()
+	client := newBotAPI("test")
+	client.baseURL = api.URL
+
+	from := &TelegramUser{ID: time.Now().UnixNano()}
+	answer := func(query string) []interface{} {
+		t.Helper()
+		if err := answerInlineQuery(context.Background(), client, &InlineQuery{ID: query, From: from, Query: query}); err != nil {
+			t.Fatalf("answerInlineQuery(%s): %v", query, err)
+		}
+		call := <-bot.sent
+		return call.Params["results"].([]interface{})
+	}
+	articleID := func(results []interface{}) string {
+		return results[0].(map[string]interface{})["id"].(string)
+	}
+
+	first := "quota_" + strconv.FormatInt(from.ID, 10)
+	if got := articleID(answer(first)); got != "summary" {
+		t.Fatalf("first search: article %q, want summary", got)
+	}
+	// Повтор из кэша бесплатен
+	if got := articleID(answer(first)); got != "summary" {
+		t.Errorf("cached search: article %q, want summary", got)
+	}
+	if got := articleID(answer("other_" + strconv.FormatInt(from.ID, 10))); got != "quota" {
+		t.Errorf("second new search: article %q, want quota", got)
+	}
+}
+
+func TestQuotaBurstChargesEverySearch(t *testing.T) {
+	limiter := &quotaLimiter{counters: map[string]*quotaCounter{}}
+	tier := QuotaTier{Burst: 5, BurstWindow: Duration(5 * time.Second)} // токен в секунду
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if exceeded := limiter.allow("k", "default", tier, 3, now); exceeded != nil {
+		t.Fatalf("3 of 5: %+v", exceeded)
+	}
+	exceeded := limiter.allow("k", "default", tier, 3, now)
+	if exceeded == nil || exceeded.Limit != "burst" {
+		t.Fatalf("3 more with 2 tokens left: %+v, want burst", exceeded)
+	}
+	if wait := exceeded.RetryAt.Sub(now); wait != time.Second {
+		t.Errorf("retry in %s, want 1s for the missing token", wait)
+	}
+	if exceeded := limiter.allow("k", "default", tier, 3, now.Add(time.Second)); exceeded != nil {
+		t.Errorf("3 after a second: %+v", exceeded)
+	}
+	if exceeded := limiter.allow("k", "default", tier, 1, now.Add(time.Second)); exceeded == nil {
+		t.Error("the bucket is empty, but a search was allowed")
+	}
+}
+
+func TestBatchQuota(t *testing.T) {
+	limiter := &quotaLimiter{counters: map[string]*quotaCounter{}}
+	tier := QuotaTier{Daily: 40, Burst: 2, BurstWindow: Duration(10 * time.Second), BatchBurst: 1}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Пакет больше burst проходит: с дневного лимита списывается каждое имя, а burst не трогается
+	if exceeded := limiter.allowBatch("k", "default", tier, 30, now); exceeded != nil {
+		t.Fatalf("batch of 30 with burst 2: %+v", exceeded)
+	}
+	if used := limiter.counters["k"].used; used != 30 {
+		t.Errorf("daily used %d after a batch of 30, want 30", used)
+	}
+	if exceeded := limiter.allow("k", "default", tier, 2, now); exceeded != nil {
+		t.Errorf("single searches after a batch: %+v, want the burst untouched", exceeded)
+	}
+
+	// Второй пакет подряд упирается в batch_burst, а не в размер
+	exceeded := limiter.allowBatch("k", "default", tier, 1, now)
+	if exceeded == nil || exceeded.Limit != "batch_burst" {
+		t.Fatalf("second batch: %+v, want batch_burst", exceeded)
+	}
+	if wait := exceeded.RetryAt.Sub(now); wait != 10*time.Second {
+		t.Errorf("retry in %s, want the whole burst_window for one batch", wait)
+	}
+
+	// Через burst_window пакет снова можно, но остаток дневного лимита — 8 имен
+	later := now.Add(10 * time.Second)
+	if exceeded := limiter.allowBatch("k", "default", tier, 9, later); exceeded == nil || exceeded.Limit != "daily" {
+		t.Errorf("9 names with 8 left today: %+v, want daily", exceeded)
+	}
+	if exceeded := limiter.allowBatch("k", "default", tier, 8, later); exceeded != nil {
+		t.Errorf("8 names with 8 left today: %+v", exceeded)
+	}
+}
+
+func TestBatchLargerThanBurst(t *testing.T) {
+	t.Setenv("TELEGRAM_BOT_TOKEN", testBotToken)
+	profiles := httptest.NewServer(http.NotFoundHandler())
+	defer profiles.Close()
+	useTestSites(t, statusSite("BatchSite", profiles.URL+"/{}"))
+
+	cfg := Settings()
+	tier := cfg.Quota.Default
+	cfg.Quota.Default = QuotaTier{Daily: 30, Burst: 2, BurstWindow: Duration(time.Minute), BatchBurst: 1}
+	t.Cleanup(func() { cfg.Quota.Default = tier })
+
+	batch := func(names int) *httptest.ResponseRecorder {
+		list := make([]string, names)
+		for i := range list {
+			list[i] = fmt.Sprintf("%q", "user"+strconv.Itoa(i))
+		}
+		r := httptest.NewRequest("POST", "/search/batch?sites=BatchSite", strings.NewReader("["+strings.Join(list, ",")+"]"))
+		r.Header.Set("Content-Type", "application/json")
+		r.RemoteAddr = "192.0.2.77:1234"
+		w := httptest.NewRecorder()
+		handleSearchBatch(w, r)
+		return w
+	}
+
+	// Пакет больше всей дневной квоты не пройдет никогда
+	if w := batch(31); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "at most 30") {
+		t.Errorf("31 names with daily 30: %d %s, want 400 naming the limit", w.Code, w.Body.String())
+	}
+	if counter := quotaLimits.counters["ip:192.0.2.77"]; counter != nil && counter.used > 0 {
+		t.Errorf("rejected batch was charged %d searches", counter.used)
+	}
+
+	// Больше burst и больше batchStreamThreshold: проходит и без Accept идет потоком
+	names := batchStreamThreshold + 1
+	w := batch(names)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%d names with burst 2: %d %s", names, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != ndjsonContentType {
+		t.Errorf("Content-Type %q, want %s", got, ndjsonContentType)
+	}
+	results := 0
+	for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+		var batchLine BatchLine
+		if err := json.Unmarshal([]byte(line), &batchLine); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		if batchLine.Type == "result" {
+			results++
+		}
+	}
+	if results != names {
+		t.Errorf("%d result lines, want %d", results, names)
+	}
+	if used := quotaLimits.counters["ip:192.0.2.77"].used; used != names {
+		t.Errorf("daily used %d, want %d: one per name", used, names)
+	}
+
+	// Второй пакет сразу — 429 по batch_burst
+	w = batch(1)
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "batch_burst") {
+		t.Errorf("second batch: %d %s, want 429 batch_burst", w.Code, w.Body.String())
+	}
+}
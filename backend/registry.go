@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SiteSnapshot — неизменяемая версия базы сайтов; поиск работает с одной версией от начала до конца
+type SiteSnapshot struct {
+	Version  string     `json:"version"`
+	Source   string     `json:"source"`
+	Count    int        `json:"count"`
+	LoadedAt time.Time  `json:"loaded_at"`
+	Sites    []SiteInfo `json:"-"`
+	hash     string
+}
+
+// byName ищет сайт по имени
+func (s *SiteSnapshot) byName(name string) (SiteInfo, bool) {
+	for _, site := range s.Sites {
+		if site.Name == name {
+			return site, true
+		}
+	}
+	return SiteInfo{}, false
+}
+
+var (
+	currentSites atomic.Pointer[SiteSnapshot]
+	reloadMu     sync.Mutex
+	reloadCount  int
+)
+
+// ReloadSites перечитывает базу из sites.source, проверяет ее и атомарно подменяет.
+// При ошибке продолжает работать предыдущая версия.
+func ReloadSites(ctx context.Context) (*SiteSnapshot, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	source := Settings().Sites.Source
+	loaded, err := LoadSiteDatabase(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSites(loaded); err != nil {
+		return nil, fmt.Errorf("%s: %w", siteSourceName(source), err)
+	}
+
+	// Хэш разобранной базы, а не файла: смена формата или отступов не дает новой версии
+	data, err := json.Marshal(loaded)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if current := currentSites.Load(); current != nil && current.hash == hash {
+		return current, nil
+	}
+
+	reloadCount++
+	snapshot := &SiteSnapshot{
+		Version:  fmt.Sprintf("v%d-%s", reloadCount, hash[:8]),
+		Source:   siteSourceName(source),
+		Count:    len(loaded),
+		LoadedAt: time.Now().UTC(),
+		Sites:    loaded,
+		hash:     hash,
+	}
+	currentSites.Store(snapshot)
+	log.Printf("Loaded %d sites from %s (version %s)", snapshot.Count, snapshot.Source, snapshot.Version)
+	return snapshot, nil
+}
+
+// WatchSiteSource перезагружает базу при изменении файла sites.source
+// (для URL и встроенной базы ничего не делает). Работает до отмены ctx.
+func WatchSiteSource(ctx context.Context) {
+	source := Settings().Sites.Source
+	interval := time.Duration(Settings().Sites.WatchInterval)
+	if source == "" || strings.Contains(source, "://") || interval <= 0 {
+		return
+	}
+
+	var lastMod time.Time
+	var lastSize int64
+	if info, err := os.Stat(source); err == nil {
+		lastMod, lastSize = info.ModTime(), info.Size()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(source)
+		if err != nil {
+			log.Printf("Error watching %s: %v", source, err)
+			continue
+		}
+		if info.ModTime().Equal(lastMod) && info.Size() == lastSize {
+			continue
+		}
+		lastMod, lastSize = info.ModTime(), info.Size()
+		if _, err := ReloadSites(ctx); err != nil {
+			log.Printf("Sites reload rejected, keeping version %s: %v", loadSites().Version, err)
+		}
+	}
+}
+
+// handleAdminSites: GET — текущая версия базы, POST — перезагрузка
+func handleAdminSites(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(loadSites())
+	case "POST":
+		snapshot, err := ReloadSites(r.Context())
+		if err != nil {
+			log.Printf("Sites reload rejected: %v", err)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   err.Error(),
+				"current": loadSites(),
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(snapshot)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
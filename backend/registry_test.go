@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// writeSiteSource перезаписывает файл sites.source, подставленный useTestSites
+func writeSiteSource(t *testing.T, data string) {
+	t.Helper()
+	if err := os.WriteFile(Settings().Sites.Source, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadSites(t *testing.T) {
+	useTestSites(t, statusSite("First", "https://first.example/{}"))
+	ctx := context.Background()
+	first := loadSites()
+	if first == nil || first.Count != 1 || !strings.HasPrefix(first.Version, "v") {
+		t.Fatalf("first snapshot %+v", first)
+	}
+
+	// Та же база в другом формате и с другими отступами — та же версия
+	writeSiteSource(t, `{"websites": [
+		{"name": "First", "base_url": "https://first.example/{}", "errorType": "status_code", "errorCode": 404}
+	]}`)
+	same, err := ReloadSites(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != first {
+		t.Errorf("identical reload made version %s, want %s kept", same.Version, first.Version)
+	}
+
+	// Сломанная база отклоняется, поиск продолжает работать с прежней версией
+	rejected := map[string]string{
+		"not JSON":       `[{"name": "First"`,
+		"no sites":       `[]`,
+		"lint error":     `[{"name": "NoURL", "errorType": "status_code", "errorCode": 404}]`,
+		"no errorCode":   `[{"name": "First", "base_url": "https://first.example/{}", "errorType": "status_code"}]`,
+		"unknown format": `{"sites": []}`,
+	}
+	for name, data := range rejected {
+		writeSiteSource(t, data)
+		if _, err := ReloadSites(ctx); err == nil {
+			t.Errorf("%s: reload accepted", name)
+		}
+		if current := loadSites(); current != first {
+			t.Errorf("%s: current version %s, want %s kept", name, current.Version, first.Version)
+		}
+	}
+
+	// Изменение — новая версия с новым счетчиком и новыми сайтами
+	writeSiteSource(t, `[
+		{"name": "First", "base_url": "https://first.example/{}", "errorType": "status_code", "errorCode": 404},
+		{"name": "Second", "base_url": "https://second.example/{}", "errorType": "status_code", "errorCode": 404}
+	]`)
+	changed, err := ReloadSites(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.Version == first.Version || changed.Count != 2 || loadSites() != changed {
+		t.Errorf("changed database: %+v, want a new current version with 2 sites after %s", changed, first.Version)
+	}
+	if _, ok := changed.byName("Second"); !ok {
+		t.Error("new version has no Second")
+	}
+	// Версия начинается со счетчика перезагрузок: v<n>-<хэш>
+	if strings.Split(changed.Version, "-")[0] == strings.Split(first.Version, "-")[0] {
+		t.Errorf("version %s after %s, want the counter bumped", changed.Version, first.Version)
+	}
+}
+
+func TestAdminSitesReload(t *testing.T) {
+	useTestSites(t, statusSite("Admin", "https://admin.example/{}"))
+	current := loadSites()
+	writeSiteSource(t, `[]`)
+
+	w := httptest.NewRecorder()
+	handleAdminSites(w, httptest.NewRequest("POST", "/admin/sites", nil))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", w.Code)
+	}
+	var body struct {
+		Error   string       `json:"error"`
+		Current SiteSnapshot `json:"current"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error == "" || body.Current.Version != current.Version {
+		t.Errorf("response %+v, want the error and version %s", body, current.Version)
+	}
+}
@@ -0,0 +1,394 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Категории сайтов (поле categories в data.json, см. sites.schema.json)
+var knownCategories = map[string]bool{
+	"social":    true, // соцсети и мессенджеры
+	"fediverse": true, // Mastodon и другие инстансы
+	"dev":       true, // хостинги кода, реестры пакетов, сервисы для разработчиков
+	"security":  true, // баг-баунти и CTF
+	"forum":     true, // форумы, агрегаторы, вопрос-ответ
+	"gaming":    true,
+	"music":     true,
+	"video":     true, // стриминг, кино, аниме
+	"art":       true, // дизайн, 3D, иллюстрации
+	"photo":     true,
+	"blog":      true,
+	"books":     true,
+	"education": true, // обучение и научные профили
+	"links":     true, // link-in-bio
+	"finance":   true, // донаты, банки
+	"shopping":  true, // маркетплейсы и объявления
+	"jobs":      true,
+	"dating":    true,
+	"travel":    true,
+	"sports":    true,
+	"news":      true,
+}
+
+// telegramCategories — категории встроенной проверки Telegram через Bot API
+var telegramCategories = []string{"social"}
+
+// SearchOptions — параметры поиска помимо имени пользователя
+type SearchOptions struct {
+	Categories        []string // только сайты из этих категорий (пусто — все)
+	ExcludeCategories []string // без сайтов из этих категорий
+	Sites             []string // только эти сайты (канонические имена); отменяет probe_budget и max_sites
+	ExcludeSites      []string // без этих сайтов
+	HeaderProfile     string   // профиль заголовков (headers.go); пусто — search.header_profile
+	Type              string   // username или email (email.go); пусто — username
+}
+
+// searchParamError — неизвестные значения в параметре поиска с похожими вариантами
+type searchParamError struct {
+	Param       string
+	Unknown     []string
+	Suggestions map[string][]string
+}
+
+func (e *searchParamError) Error() string {
+	return fmt.Sprintf("%s: unknown %s", e.Param, strings.Join(e.Unknown, ", "))
+}
+
+// add запоминает неизвестное значение и до трех похожих вариантов
+func (e *searchParamError) add(value string, candidates []string) {
+	e.Unknown = append(e.Unknown, value)
+	if matches := closeMatches(value, candidates, 3); len(matches) > 0 {
+		if e.Suggestions == nil {
+			e.Suggestions = map[string][]string{}
+		}
+		e.Suggestions[value] = matches
+	}
+}
+
+// parseSearchOptions читает categories=, exclude_categories=, sites= и exclude_sites=
+// (через запятую или повтором параметра), profile= и type=. Имена сайтов сверяются со snapshot.
+func parseSearchOptions(query url.Values, snapshot *SiteSnapshot) (SearchOptions, error) {
+	var opts SearchOptions
+	var err error
+	if opts.Categories, err = parseCategoryList("categories", query["categories"]); err != nil {
+		return opts, err
+	}
+	if opts.ExcludeCategories, err = parseCategoryList("exclude_categories", query["exclude_categories"]); err != nil {
+		return opts, err
+	}
+	if opts.Sites, err = parseSiteList("sites", query["sites"], snapshot); err != nil {
+		return opts, err
+	}
+	if opts.ExcludeSites, err = parseSiteList("exclude_sites", query["exclude_sites"], snapshot); err != nil {
+		return opts, err
+	}
+	if opts.HeaderProfile, err = parseHeaderProfile("profile", query.Get("profile")); err != nil {
+		return opts, err
+	}
+	switch searchType := strings.ToLower(strings.TrimSpace(query.Get("type"))); searchType {
+	case "", SearchTypeUsername, SearchTypeEmail:
+		opts.Type = searchType
+	default:
+		bad := &searchParamError{Param: "type"}
+		bad.add(query.Get("type"), []string{SearchTypeUsername, SearchTypeEmail})
+		return opts, bad
+	}
+	return opts, nil
+}
+
+// parseHeaderProfile проверяет имя профиля заголовков; "random" тоже допустим
+func parseHeaderProfile(param, value string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	if name == "" || name == headerProfileRandom {
+		return name, nil
+	}
+	if _, ok := headerProfiles[name]; !ok {
+		bad := &searchParamError{Param: param}
+		bad.add(value, headerProfileNames())
+		return "", bad
+	}
+	return name, nil
+}
+
+// splitParam разбивает значения вида "a,b" и повторы параметра
+func splitParam(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func parseCategoryList(param string, values []string) ([]string, error) {
+	var list []string
+	bad := &searchParamError{Param: param}
+	for _, item := range splitParam(values) {
+		category := strings.ToLower(item)
+		if !knownCategories[category] {
+			bad.add(item, sortedKeys(knownCategories))
+			continue
+		}
+		list = append(list, category)
+	}
+	if len(bad.Unknown) > 0 {
+		return nil, bad
+	}
+	return list, nil
+}
+
+// parseSiteList переводит имена и алиасы в канонические имена сайтов
+func parseSiteList(param string, values []string, snapshot *SiteSnapshot) ([]string, error) {
+	items := splitParam(values)
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	index := map[string]string{}
+	var candidates []string
+	for _, site := range snapshot.Sites {
+		for _, key := range append([]string{site.Name}, site.Aliases...) {
+			index[normalizeSiteName(key)] = site.Name
+			candidates = append(candidates, key)
+		}
+	}
+	if _, ok := index[normalizeSiteName("Telegram")]; !ok {
+		index[normalizeSiteName("Telegram")] = "Telegram"
+		candidates = append(candidates, "Telegram")
+	}
+
+	var list []string
+	bad := &searchParamError{Param: param}
+	for _, item := range items {
+		name, ok := index[normalizeSiteName(item)]
+		if !ok {
+			bad.add(item, candidates)
+			continue
+		}
+		list = append(list, name)
+	}
+	if len(bad.Unknown) > 0 {
+		return nil, bad
+	}
+	return list, nil
+}
+
+// normalizeSiteName сравнивает имена без регистра, пробелов и знаков: "Twitter/X" == "twitterx"
+func normalizeSiteName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// closeMatches возвращает до n кандидатов, похожих на value (по расстоянию Левенштейна или префиксу)
+func closeMatches(value string, candidates []string, n int) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+	target := normalizeSiteName(value)
+	limit := len([]rune(target))/3 + 1
+	seen := map[string]bool{}
+	var matches []match
+	for _, candidate := range candidates {
+		key := normalizeSiteName(candidate)
+		if seen[candidate] || key == "" {
+			continue
+		}
+		seen[candidate] = true
+		distance := levenshtein(target, key)
+		if target != "" && (strings.HasPrefix(key, target) || strings.HasPrefix(target, key)) {
+			distance = 0
+		}
+		if distance <= limit {
+			matches = append(matches, match{candidate, distance})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].distance < matches[j].distance })
+	var names []string
+	for i := 0; i < len(matches) && i < n; i++ {
+		names = append(names, matches[i].name)
+	}
+	return names
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// allowsSite решает, проверять ли сайт с таким именем и категориями
+func (o SearchOptions) allowsSite(name string, categories []string) bool {
+	if containsString(o.ExcludeSites, name) || hasAnyCategory(categories, o.ExcludeCategories) {
+		return false
+	}
+	if len(o.Sites) > 0 && !containsString(o.Sites, name) {
+		return false
+	}
+	return len(o.Categories) == 0 || hasAnyCategory(categories, o.Categories)
+}
+
+// filter оставляет сайты, подходящие под фильтры
+func (o SearchOptions) filter(sites []SiteInfo) []SiteInfo {
+	if len(o.Categories)+len(o.ExcludeCategories)+len(o.Sites)+len(o.ExcludeSites) == 0 {
+		return sites
+	}
+	filtered := make([]SiteInfo, 0, len(sites))
+	for _, site := range sites {
+		if o.allowsSite(site.Name, site.Categories) {
+			filtered = append(filtered, site)
+		}
+	}
+	return filtered
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyCategory(categories, wanted []string) bool {
+	for _, category := range categories {
+		for _, w := range wanted {
+			if category == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// groupByCategory раскладывает найденные сайты по категориям; сайт без категорий попадает в "other"
+func groupByCategory(found []string, snapshot *SiteSnapshot) map[string][]string {
+	groups := map[string][]string{}
+	for _, name := range found {
+		var categories []string
+		if site, ok := snapshot.byName(name); ok {
+			categories = site.Categories
+		} else if name == "Telegram" {
+			categories = telegramCategories
+		}
+		if len(categories) == 0 {
+			categories = []string{"other"}
+		}
+		for _, category := range categories {
+			groups[category] = append(groups[category], name)
+		}
+	}
+	for _, names := range groups {
+		sort.Strings(names)
+	}
+	return groups
+}
+
+// searchParams собирает параметры /search из query и тела POST (JSON или форма)
+func searchParams(r *http.Request) (url.Values, error) {
+	params := r.URL.Query()
+	if r.Method != "POST" {
+		return params, nil
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body searchBody
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&body); err != nil {
+			return nil, err
+		}
+		body.apply(params)
+		return params, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	return r.Form, nil
+}
+
+// searchBody — параметры поиска в JSON-теле POST
+type searchBody struct {
+	Username          string            `json:"username"`
+	Usernames         oneOrMany[string] `json:"usernames"` // только для /search/batch
+	Categories        oneOrMany[string] `json:"categories"`
+	ExcludeCategories oneOrMany[string] `json:"exclude_categories"`
+	Sites             oneOrMany[string] `json:"sites"`
+	ExcludeSites      oneOrMany[string] `json:"exclude_sites"`
+	Profile           string            `json:"profile"`
+	Type              string            `json:"type"`
+	Email             string            `json:"email"`
+}
+
+// apply дописывает заданные поля тела к параметрам запроса
+func (body searchBody) apply(params url.Values) {
+	for key, value := range map[string]string{"username": body.Username, "profile": body.Profile, "type": body.Type, "email": body.Email} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	for key, list := range map[string][]string{
+		"usernames":          body.Usernames,
+		"categories":         body.Categories,
+		"exclude_categories": body.ExcludeCategories,
+		"sites":              body.Sites,
+		"exclude_sites":      body.ExcludeSites,
+	} {
+		if len(list) > 0 {
+			params[key] = append(params[key], list...)
+		}
+	}
+}
+
+// writeSearchParamError отвечает 400 со списком неизвестных значений и подсказками
+func writeSearchParamError(w http.ResponseWriter, r *http.Request, err error) {
+	var bad *searchParamError
+	if !errors.As(err, &bad) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if isV1(r) {
+		writeAPIError(w, http.StatusBadRequest, codeInvalidParameter,
+			fmt.Sprintf("Unknown values in parameter %s: %s", bad.Param, strings.Join(bad.Unknown, ", ")),
+			map[string]interface{}{"param": bad.Param, "unknown": bad.Unknown, "suggestions": bad.Suggestions})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":       fmt.Sprintf("Неизвестные значения в параметре %s: %s", bad.Param, strings.Join(bad.Unknown, ", ")),
+		"param":       bad.Param,
+		"unknown":     bad.Unknown,
+		"suggestions": bad.Suggestions,
+	})
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// categorySnapshot — сайты с разными наборами категорий
+func categorySnapshot() *SiteSnapshot {
+	return &SiteSnapshot{Sites: []SiteInfo{
+		{Name: "GitHub", Categories: []string{"dev"}},
+		{Name: "HackerOne", Categories: []string{"security", "dev"}},
+		{Name: "Mastodon", Categories: []string{"social", "fediverse"}},
+		{Name: "Steam", Categories: []string{"gaming"}},
+		{Name: "Twitter/X", Categories: []string{"social"}, Aliases: []string{"twitter", "x"}},
+		{Name: "Plain"},
+	}}
+}
+
+func siteNames(sites []SiteInfo) []string {
+	var names []string
+	for _, site := range sites {
+		names = append(names, site.Name)
+	}
+	return names
+}
+
+func TestFilterByCategories(t *testing.T) {
+	tests := []struct {
+		name string
+		opts SearchOptions
+		want []string
+	}{
+		{"no filters", SearchOptions{}, []string{"GitHub", "HackerOne", "Mastodon", "Steam", "Twitter/X", "Plain"}},
+		{"one category", SearchOptions{Categories: []string{"dev"}}, []string{"GitHub", "HackerOne"}},
+		{"any of categories", SearchOptions{Categories: []string{"gaming", "fediverse"}}, []string{"Mastodon", "Steam"}},
+		// Исключение важнее включения: сайт из обеих категорий не проверяется
+		{"exclude wins", SearchOptions{Categories: []string{"dev"}, ExcludeCategories: []string{"security"}}, []string{"GitHub"}},
+		// Сайт без категорий проходит только без фильтра categories=
+		{"exclude only", SearchOptions{ExcludeCategories: []string{"social"}}, []string{"GitHub", "HackerOne", "Steam", "Plain"}},
+		{"category without sites", SearchOptions{Categories: []string{"dating"}}, nil},
+		{"sites and categories", SearchOptions{Categories: []string{"social"}, Sites: []string{"GitHub", "Mastodon"}}, []string{"Mastodon"}},
+		{"exclude site", SearchOptions{Categories: []string{"social"}, ExcludeSites: []string{"Twitter/X"}}, []string{"Mastodon"}},
+	}
+	for _, tt := range tests {
+		if got := siteNames(tt.opts.filter(categorySnapshot().Sites)); !equalStrings(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTelegramFollowsCategories(t *testing.T) {
+	tests := []struct {
+		opts SearchOptions
+		want bool
+	}{
+		{SearchOptions{}, true},
+		{SearchOptions{Categories: []string{"social"}}, true},
+		{SearchOptions{Categories: []string{"dev"}}, false},
+		{SearchOptions{ExcludeCategories: []string{"social"}}, false},
+		{SearchOptions{Sites: []string{"Telegram"}}, true},
+		{SearchOptions{ExcludeSites: []string{"Telegram"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.allowsSite("Telegram", telegramCategories); got != tt.want {
+			t.Errorf("allowsSite(Telegram) with %+v = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestParseCategoryList(t *testing.T) {
+	query := url.Values{"categories": {"Dev, gaming", "social"}, "exclude_categories": {"security,"}}
+	opts, err := parseSearchOptions(query, categorySnapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dev", "gaming", "social"}; !equalStrings(opts.Categories, want) {
+		t.Errorf("categories = %v, want %v", opts.Categories, want)
+	}
+	if want := []string{"security"}; !equalStrings(opts.ExcludeCategories, want) {
+		t.Errorf("exclude_categories = %v, want %v", opts.ExcludeCategories, want)
+	}
+
+	_, err = parseSearchOptions(url.Values{"categories": {"dev,gamng,crypto"}}, categorySnapshot())
+	var bad *searchParamError
+	if !errors.As(err, &bad) || bad.Param != "categories" {
+		t.Fatalf("err = %v, want a categories error", err)
+	}
+	if !equalStrings(bad.Unknown, []string{"gamng", "crypto"}) {
+		t.Errorf("unknown = %v, want [gamng crypto]", bad.Unknown)
+	}
+	if got := bad.Suggestions["gamng"]; len(got) == 0 || got[0] != "gaming" {
+		t.Errorf("suggestions for gamng = %v, want gaming first", got)
+	}
+}
+
+func TestGroupByCategory(t *testing.T) {
+	found := []string{"Twitter/X", "HackerOne", "Telegram", "Plain", "GitHub", "Mastodon", "Deleted"}
+	want := map[string][]string{
+		"dev":       {"GitHub", "HackerOne"},
+		"security":  {"HackerOne"},
+		"social":    {"Mastodon", "Telegram", "Twitter/X"},
+		"fediverse": {"Mastodon"},
+		// Сайт без категорий и сайт, которого уже нет в базе
+		"other": {"Deleted", "Plain"},
+	}
+	if got := groupByCategory(found, categorySnapshot()); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupByCategory = %v, want %v", got, want)
+	}
+	if got := groupByCategory(nil, categorySnapshot()); len(got) != 0 {
+		t.Errorf("groupByCategory(nil) = %v, want empty", got)
+	}
+}
+
+func TestParseSiteList(t *testing.T) {
+	snapshot := categorySnapshot()
+	tests := []struct {
+		values []string
+		want   []string
+	}{
+		{[]string{"GitHub"}, []string{"GitHub"}},
+		// Имена и алиасы без учета регистра, пробелов и знаков
+		{[]string{"twitter"}, []string{"Twitter/X"}},
+		{[]string{"X"}, []string{"Twitter/X"}},
+		{[]string{"TWITTER / x"}, []string{"Twitter/X"}},
+		{[]string{" github , hacker-one", "Steam"}, []string{"GitHub", "HackerOne", "Steam"}},
+		// Telegram проверяется через Bot API и в базе не описан
+		{[]string{"telegram"}, []string{"Telegram"}},
+		{[]string{"", " , "}, nil},
+	}
+	for _, tt := range tests {
+		got, err := parseSiteList("sites", tt.values, snapshot)
+		if err != nil || !equalStrings(got, tt.want) {
+			t.Errorf("parseSiteList(%q) = %v, %v; want %v", tt.values, got, err, tt.want)
+		}
+	}
+
+	_, err := parseSiteList("exclude_sites", []string{"GitHib,Mastodon,Nowhere"}, snapshot)
+	var bad *searchParamError
+	if !errors.As(err, &bad) || bad.Param != "exclude_sites" {
+		t.Fatalf("err = %v, want an exclude_sites error", err)
+	}
+	if !equalStrings(bad.Unknown, []string{"GitHib", "Nowhere"}) {
+		t.Errorf("unknown = %v, want [GitHib Nowhere]", bad.Unknown)
+	}
+	if got := bad.Suggestions["GitHib"]; !equalStrings(got, []string{"GitHub"}) {
+		t.Errorf("suggestions for GitHib = %v, want [GitHub]", got)
+	}
+	if got, ok := bad.Suggestions["Nowhere"]; ok {
+		t.Errorf("suggestions for Nowhere = %v, want none", got)
+	}
+}
+
+func TestCloseMatches(t *testing.T) {
+	candidates := []string{"GitHub", "GitLab", "Gitee", "Twitter/X", "twitter", "Mastodon", "Steam", "Stream"}
+	tests := []struct {
+		value string
+		n     int
+		want  []string
+	}{
+		// Ближайшие первыми, не дальше трети длины имени
+		{"githb", 3, []string{"GitHub", "GitLab", "Gitee"}},
+		{"githb", 1, []string{"GitHub"}},
+		// Префикс считается точным попаданием
+		{"git", 3, []string{"GitHub", "GitLab", "Gitee"}},
+		{"git", 2, []string{"GitHub", "GitLab"}},
+		{"twiter", 2, []string{"twitter", "Twitter/X"}},
+		{"Steem", 3, []string{"Steam", "Stream"}},
+		{"mastodon.social", 3, []string{"Mastodon"}},
+		{"facebook", 3, nil},
+		{"", 3, nil},
+	}
+	for _, tt := range tests {
+		if got := closeMatches(tt.value, candidates, tt.n); !equalStrings(got, tt.want) {
+			t.Errorf("closeMatches(%q, %d) = %v, want %v", tt.value, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Итог самопроверки сайта
+const (
+	SelfTestPass          = "pass"
+	SelfTestFalsePositive = "false_positive" // профиль "найден" у known_absent
+	SelfTestFalseNegative = "false_negative" // профиль не найден у known_present
+	SelfTestBlocked       = "blocked"        // сайт не дал ответа по существу
+	SelfTestUnconfirmed   = "unconfirmed"    // правило не подтверждено (errorType unknown): в отчете только коды ответов
+)
+
+// maxFixtureBodySize — сколько тела ответа сохраняем в фикстуру
+const maxFixtureBodySize = 4 << 20
+
+// selfTestAbsentUsername — имя, которого точно нет, если у сайта не задан known_absent
+const selfTestAbsentUsername = "gosearchnoonewouldusethis7"
+
+// Ответы, которые означают защиту от ботов или лимиты, а не отсутствие профиля
+var blockedStatuses = map[int]bool{
+	http.StatusUnauthorized:       true,
+	http.StatusForbidden:          true,
+	http.StatusTooManyRequests:    true,
+	http.StatusServiceUnavailable: true,
+}
+
+// SelfTestCase — проверка одного известного имени
+type SelfTestCase struct {
+	Username string `json:"username"`
+	Expect   string `json:"expect"`        // present или absent
+	Got      string `json:"got,omitempty"` // present, absent или пусто, если ответа нет или правило не подтверждено
+	Status   int    `json:"status,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SelfTestResult — итог по сайту: худший из исходов его проверок
+type SelfTestResult struct {
+	Site   string         `json:"site"`
+	Status string         `json:"status"`
+	Cases  []SelfTestCase `json:"cases"`
+	// SuggestedCode — errorCode для status_code, если ответы неподтвержденного правила его однозначно показывают
+	SuggestedCode int `json:"suggested_code,omitempty"`
+}
+
+// SelfTestReport — отчет о здоровье правил
+type SelfTestReport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Summary     map[string]int   `json:"summary"`
+	Sites       []SelfTestResult `json:"sites"`
+}
+
+// SelfTestSites проверяет правила сайтов на known_present и known_absent.
+// Запросы идут через client, так что для офлайн-проверки достаточно FixtureTransport.
+func SelfTestSites(ctx context.Context, client *http.Client, list []SiteInfo, concurrency int) *SelfTestReport {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	results := make([]SelfTestResult, len(list))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, site := range list {
+		wg.Add(1)
+		go func(i int, site SiteInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = selfTestSite(ctx, client, site)
+		}(i, site)
+	}
+	wg.Wait()
+
+	report := &SelfTestReport{GeneratedAt: time.Now().UTC(), Summary: map[string]int{}, Sites: results}
+	for _, result := range results {
+		report.Summary[result.Status]++
+	}
+	return report
+}
+
+func selfTestSite(ctx context.Context, client *http.Client, site SiteInfo) SelfTestResult {
+	result := SelfTestResult{Site: site.Name, Status: SelfTestPass, Cases: []SelfTestCase{}}
+	unconfirmed := !checkable(site)
+	if unconfirmed {
+		// Правила нет, но коды ответов на known_present и known_absent помогают его подобрать
+		result.Status = SelfTestUnconfirmed
+	}
+
+	absent := site.KnownAbsent
+	if len(absent) == 0 {
+		absent = []string{selfTestAbsentUsername}
+	}
+	profile := pickHeaderProfile("")
+	var cases []SelfTestCase
+	for _, username := range site.KnownPresent {
+		cases = append(cases, SelfTestCase{Username: username, Expect: "present"})
+	}
+	for _, username := range absent {
+		cases = append(cases, SelfTestCase{Username: username, Expect: "absent"})
+	}
+
+	for _, c := range cases {
+		status, body, err := fetchProfile(ctx, client, site, profile, c.Username)
+		c.Status = status
+		switch {
+		case err != nil:
+			c.Error = err.Error()
+		case unconfirmed:
+			// Только код ответа: истолковать его пока нечем
+		case isBlockedResponse(site, status):
+			c.Error = fmt.Sprintf("HTTP %d", status)
+		default:
+			found, _ := matchRule(site, status, body)
+			c.Got = "absent"
+			if found {
+				c.Got = "present"
+			}
+		}
+		result.Cases = append(result.Cases, c)
+		if !unconfirmed {
+			result.Status = worseSelfTestStatus(result.Status, caseStatus(c))
+		}
+	}
+	if unconfirmed {
+		result.SuggestedCode = suggestErrorCode(result.Cases)
+	}
+	return result
+}
+
+// suggestErrorCode — код «нет профиля», если все known_present ответили 2xx, а все known_absent —
+// одним и тем же кодом 4xx/5xx, который не похож на защиту от ботов. Иначе 0.
+func suggestErrorCode(cases []SelfTestCase) int {
+	code, present := 0, false
+	for _, c := range cases {
+		if c.Error != "" {
+			return 0
+		}
+		if c.Expect == "present" {
+			if c.Status < 200 || c.Status > 299 {
+				return 0
+			}
+			present = true
+			continue
+		}
+		if c.Status < 400 || blockedStatuses[c.Status] || (code != 0 && c.Status != code) {
+			return 0
+		}
+		code = c.Status
+	}
+	if !present {
+		return 0 // без существующего профиля не видно, отличается ли ответ
+	}
+	return code
+}
+
+// isBlockedResponse — сайт ответил защитой или лимитом, а не страницей профиля.
+// Исключение — status_code, у которого этот код и есть признак отсутствия профиля.
+func isBlockedResponse(site SiteInfo, status int) bool {
+	if !blockedStatuses[status] {
+		return false
+	}
+	code, ok := errorCodeInt(site.ErrorCode)
+	return !(site.ErrorType == "status_code" && ok && code == status)
+}
+
+func caseStatus(c SelfTestCase) string {
+	switch {
+	case c.Got == "":
+		return SelfTestBlocked
+	case c.Got == c.Expect:
+		return SelfTestPass
+	case c.Expect == "present":
+		return SelfTestFalseNegative
+	default:
+		return SelfTestFalsePositive
+	}
+}
+
+// Порядок важности: ошибка правила важнее блокировки, блокировка важнее успеха
+var selfTestSeverity = map[string]int{
+	SelfTestPass:          0,
+	SelfTestUnconfirmed:   0,
+	SelfTestBlocked:       1,
+	SelfTestFalseNegative: 2,
+	SelfTestFalsePositive: 3,
+}
+
+func worseSelfTestStatus(a, b string) string {
+	if selfTestSeverity[b] > selfTestSeverity[a] {
+		return b
+	}
+	return a
+}
+
+// Failed — есть ли сайты с неверным правилом
+func (r *SelfTestReport) Failed() bool {
+	return r.Summary[SelfTestFalsePositive]+r.Summary[SelfTestFalseNegative] > 0
+}
+
+// Markdown — отчет для issue или PR: сводка и таблица проблемных сайтов
+func (r *SelfTestReport) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Site self-test\n\nGenerated %s\n\n", r.GeneratedAt.Format(time.RFC3339))
+	b.WriteString("| Status | Sites |\n|---|---|\n")
+	for _, status := range []string{SelfTestPass, SelfTestFalsePositive, SelfTestFalseNegative, SelfTestBlocked, SelfTestUnconfirmed} {
+		fmt.Fprintf(&b, "| %s | %d |\n", status, r.Summary[status])
+	}
+
+	var problems []SelfTestResult
+	for _, result := range r.Sites {
+		if result.Status != SelfTestPass {
+			problems = append(problems, result)
+		}
+	}
+	if len(problems) == 0 {
+		return b.String()
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return selfTestSeverity[problems[i].Status] > selfTestSeverity[problems[j].Status]
+	})
+
+	b.WriteString("\n| Site | Status | Details |\n|---|---|---|\n")
+	for _, result := range problems {
+		var details []string
+		if result.Status == SelfTestUnconfirmed {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(result.Site), result.Status, markdownCell(unconfirmedDetails(result)))
+			continue
+		}
+		for _, c := range result.Cases {
+			if caseStatus(c) == SelfTestPass {
+				continue
+			}
+			got := c.Got
+			if got == "" {
+				got = c.Error
+			}
+			details = append(details, fmt.Sprintf("`%s`: expected %s, got %s", c.Username, c.Expect, got))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(result.Site), result.Status, markdownCell(strings.Join(details, "; ")))
+	}
+	return b.String()
+}
+
+// unconfirmedDetails — коды ответов неподтвержденного правила и подсказка errorCode
+func unconfirmedDetails(result SelfTestResult) string {
+	var details []string
+	for _, c := range result.Cases {
+		got := c.Error
+		if got == "" {
+			got = fmt.Sprintf("HTTP %d", c.Status)
+		}
+		details = append(details, fmt.Sprintf("`%s` (%s): %s", c.Username, c.Expect, got))
+	}
+	if result.SuggestedCode != 0 {
+		details = append(details, fmt.Sprintf("suggested errorCode %d", result.SuggestedCode))
+	}
+	return strings.Join(details, "; ")
+}
+
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+// --- Записанные ответы для офлайн-проверки ---
+
+// recordedResponse — ответ сайта в каталоге фикстур, один файл на URL
+type recordedResponse struct {
+	URL      string `json:"url"`
+	Status   int    `json:"status"`
+	Location string `json:"location,omitempty"` // для редиректов
+	Body     string `json:"body"`
+}
+
+func fixturePath(dir, rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// FixtureTransport отвечает записанными ответами из dir; неизвестный URL — ошибка
+func FixtureTransport(dir string) http.RoundTripper {
+	return fixtureTransport{dir: dir}
+}
+
+type fixtureTransport struct {
+	dir string
+}
+
+func (t fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(fixturePath(t.dir, req.URL.String()))
+	if err != nil {
+		return nil, fmt.Errorf("no fixture for %s", req.URL)
+	}
+	var rec recordedResponse
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("fixture for %s: %w", req.URL, err)
+	}
+	header := http.Header{}
+	if rec.Location != "" {
+		header.Set("Location", rec.Location)
+	}
+	return &http.Response{
+		StatusCode: rec.Status,
+		Status:     fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(rec.Body)),
+		Request:    req,
+	}, nil
+}
+
+// RecordingTransport пропускает запросы через next и сохраняет ответы в dir
+func RecordingTransport(dir string, next http.RoundTripper) http.RoundTripper {
+	return recordingTransport{dir: dir, next: next}
+}
+
+type recordingTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFixtureBodySize))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	data, err := json.MarshalIndent(recordedResponse{
+		URL:      req.URL.String(),
+		Status:   resp.StatusCode,
+		Location: resp.Header.Get("Location"),
+		Body:     string(body),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(fixturePath(t.dir, req.URL.String()), data, 0o644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
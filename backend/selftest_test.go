@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Фикстуры в testdata/selftest записаны вручную в формате sites selftest -record
+const selfTestFixtures = "testdata/selftest/fixtures"
+
+func loadSelfTestSites(t *testing.T) []SiteInfo {
+	t.Helper()
+	data, err := os.ReadFile("testdata/selftest/sites.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []SiteInfo
+	if err := json.Unmarshal(data, &list); err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestSelfTestFixtures(t *testing.T) {
+	client := &http.Client{Transport: FixtureTransport(selfTestFixtures)}
+	report := SelfTestSites(context.Background(), client, loadSelfTestSites(t), 4)
+
+	want := map[string]string{
+		"Status":      SelfTestPass,
+		"Message":     SelfTestPass,
+		"Soft404":     SelfTestFalsePositive,
+		"Renamed":     SelfTestFalseNegative,
+		"Guarded":     SelfTestBlocked,
+		"Unconfirmed": SelfTestUnconfirmed, // пробуется, но только с кодами ответов
+		"Redirect":    SelfTestPass,        // 302 не должен уходить на /login, для которого нет фикстуры
+	}
+	if len(report.Sites) != len(want) {
+		t.Fatalf("report has %d sites, want %d", len(report.Sites), len(want))
+	}
+	for _, result := range report.Sites {
+		if result.Status != want[result.Site] {
+			t.Errorf("%s: status %s, want %s; cases %+v", result.Site, result.Status, want[result.Site], result.Cases)
+		}
+	}
+
+	summary := map[string]int{SelfTestPass: 3, SelfTestFalsePositive: 1, SelfTestFalseNegative: 1, SelfTestBlocked: 1, SelfTestUnconfirmed: 1}
+	for status, count := range summary {
+		if report.Summary[status] != count {
+			t.Errorf("summary[%s] = %d, want %d", status, report.Summary[status], count)
+		}
+	}
+	if !report.Failed() {
+		t.Error("Failed() = false with a false positive and a false negative")
+	}
+
+	markdown := report.Markdown()
+	for _, row := range []string{
+		"| Soft404 | false_positive | `ghost`: expected absent, got present |",
+		"| Renamed | false_negative | `alice`: expected present, got absent |",
+		"| Guarded | blocked | `alice`: expected present, got HTTP 403 |",
+		"| Unconfirmed | unconfirmed | `alice` (present): HTTP 200; `ghost` (absent): HTTP 404; suggested errorCode 404 |",
+	} {
+		if !strings.Contains(markdown, row) {
+			t.Errorf("markdown has no row %q:\n%s", row, markdown)
+		}
+	}
+	if strings.Contains(markdown, "| Message |") {
+		t.Errorf("markdown lists a passing site:\n%s", markdown)
+	}
+}
+
+func TestSuggestErrorCode(t *testing.T) {
+	present := func(status int) SelfTestCase {
+		return SelfTestCase{Username: "alice", Expect: "present", Status: status}
+	}
+	absent := func(status int) SelfTestCase {
+		return SelfTestCase{Username: "ghost", Expect: "absent", Status: status}
+	}
+	tests := []struct {
+		name  string
+		cases []SelfTestCase
+		want  int
+	}{
+		{"404 for a missing profile", []SelfTestCase{present(200), absent(404)}, 404},
+		{"410 for every missing profile", []SelfTestCase{present(200), present(204), absent(410), absent(410)}, 410},
+		{"soft 404", []SelfTestCase{present(200), absent(200)}, 0},
+		{"redirect", []SelfTestCase{present(200), absent(302)}, 0},
+		{"different codes", []SelfTestCase{present(200), absent(404), absent(410)}, 0},
+		{"present not 2xx", []SelfTestCase{present(404), absent(404)}, 0},
+		{"bot protection", []SelfTestCase{present(200), absent(403)}, 0},
+		{"no known_present", []SelfTestCase{absent(404)}, 0},
+		{"request failed", []SelfTestCase{present(200), {Username: "ghost", Expect: "absent", Error: "timeout"}}, 0},
+	}
+	for _, tt := range tests {
+		if got := suggestErrorCode(tt.cases); got != tt.want {
+			t.Errorf("%s: suggestErrorCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFixtureTransportUnknownURL(t *testing.T) {
+	client := &http.Client{Transport: FixtureTransport(selfTestFixtures)}
+	if _, err := client.Get("https://status.example/bob"); err == nil || !strings.Contains(err.Error(), "no fixture") {
+		t.Errorf("unrecorded URL: err = %v, want no fixture", err)
+	}
+}
+
+func TestRecordingTransportReplays(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/alice" {
+			w.Write([]byte("<h1>alice</h1>"))
+			return
+		}
+		http.Error(w, "User not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	site := SiteInfo{Name: "Live", BaseURL: server.URL + "/{}", ErrorType: "errorMsg", ErrorMsg: "User not found", KnownPresent: []string{"alice"}}
+	recording := &http.Client{Transport: RecordingTransport(dir, http.DefaultTransport)}
+	live := SelfTestSites(context.Background(), recording, []SiteInfo{site}, 1)
+	server.Close()
+
+	replay := &http.Client{Transport: FixtureTransport(dir)}
+	offline := SelfTestSites(context.Background(), replay, []SiteInfo{site}, 1)
+	if live.Sites[0].Status != SelfTestPass || offline.Sites[0].Status != SelfTestPass {
+		t.Errorf("live %s, offline %s; want both %s (cases %+v)", live.Sites[0].Status, offline.Sites[0].Status, SelfTestPass, offline.Sites[0].Cases)
+	}
+}
+
+func TestSelfTestFixtureSitesLint(t *testing.T) {
+	for _, issue := range LintSites(loadSelfTestSites(t)) {
+		if issue.Severity == SeverityError {
+			t.Errorf("%s: %s", issue.Site, issue.Message)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Встроенная база сайтов — используется, если sites.source не задан
+//
+//go:embed data.json
+var embeddedSitesData []byte
+
+// Ограничение на размер базы, скачиваемой по URL
+const maxSiteDatabaseSize = 16 << 20
+
+// LoadSiteDatabase загружает базу сайтов из source: пустая строка — встроенная
+// data.json, https://... — URL, иначе путь к файлу.
+func LoadSiteDatabase(ctx context.Context, source string) ([]SiteInfo, error) {
+	data, err := readSiteSource(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	loaded, err := parseSiteDatabase(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", siteSourceName(source), err)
+	}
+	return loaded, nil
+}
+
+// siteSourceName — источник базы для логов
+func siteSourceName(source string) string {
+	if source == "" {
+		return "embedded data.json"
+	}
+	return source
+}
+
+func readSiteSource(ctx context.Context, source string) ([]byte, error) {
+	if source == "" {
+		return embeddedSitesData, nil
+	}
+	if !strings.Contains(source, "://") {
+		return os.ReadFile(source)
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("sites source: %w", err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("sites source %s: only https URLs are allowed", source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sites source %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sites source %s: HTTP %d", source, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSiteDatabaseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("sites source %s: %w", source, err)
+	}
+	if len(data) > maxSiteDatabaseSize {
+		return nil, fmt.Errorf("sites source %s: larger than %d bytes", source, maxSiteDatabaseSize)
+	}
+	return data, nil
+}
+
+// parseSiteDatabase понимает оба формата: голый массив и {"websites": [...]}
+func parseSiteDatabase(data []byte) ([]SiteInfo, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("site database is empty")
+	}
+
+	var loaded []SiteInfo
+	switch data[0] {
+	case '[':
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return nil, err
+		}
+	case '{':
+		var wrapped struct {
+			Websites *[]SiteInfo `json:"websites"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, err
+		}
+		if wrapped.Websites == nil {
+			return nil, errors.New(`site database object has no "websites" array`)
+		}
+		loaded = *wrapped.Websites
+	default:
+		return nil, errors.New("site database must be a JSON array or an object with \"websites\"")
+	}
+
+	if len(loaded) == 0 {
+		return nil, errors.New("site database has no sites")
+	}
+	return loaded, nil
+}
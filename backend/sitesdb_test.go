@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSiteDatabaseFormats(t *testing.T) {
+	want := []SiteInfo{
+		{Name: "GitHub", BaseURL: "https://github.com/{}", ErrorType: "status_code", ErrorCode: float64(404)}, // так errorCode приходит из JSON
+		{Name: "Forum", BaseURL: "https://forum.example/u/{}", ErrorType: "errorMsg", ErrorMsg: "No such user"},
+	}
+	sites := `[
+		{"name": "GitHub", "base_url": "https://github.com/{}", "errorType": "status_code", "errorCode": 404},
+		{"name": "Forum", "base_url": "https://forum.example/u/{}", "errorType": "errorMsg", "errorMsg": "No such user"}
+	]`
+	formats := map[string]string{
+		"bare array":        sites,
+		"websites wrapper":  `{"websites": ` + sites + `}`,
+		"surrounding space": "\n\t " + sites + "\n",
+		"extra fields":      `{"version": 3, "websites": ` + sites + `, "generated": "2026-01-01"}`,
+	}
+	for name, data := range formats {
+		got, err := parseSiteDatabase([]byte(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestParseSiteDatabaseRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // подстрока ошибки
+	}{
+		{"empty", "", "empty"},
+		{"only whitespace", " \n\t", "empty"},
+		{"scalar", "42", "JSON array or an object"},
+		{"string", `"sites"`, "JSON array or an object"},
+		{"truncated array", `[{"name": "GitHub"`, "unexpected end"},
+		{"object without websites", `{"sites": []}`, `no "websites" array`},
+		{"null websites", `{"websites": null}`, `no "websites" array`},
+		{"websites not an array", `{"websites": {"name": "GitHub"}}`, "cannot unmarshal"},
+		{"empty array", `[]`, "no sites"},
+		{"empty websites", `{"websites": []}`, "no sites"},
+		{"wrong field type", `[{"name": 42}]`, "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sites, err := parseSiteDatabase([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseSiteDatabase(%q) = %d sites, %v; want an error with %q", tt.data, len(sites), err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSiteDatabase(t *testing.T) {
+	ctx := context.Background()
+	embedded, err := LoadSiteDatabase(ctx, "")
+	if err != nil || len(embedded) == 0 {
+		t.Fatalf("embedded data.json: %d sites, %v", len(embedded), err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sites.json")
+	if err := os.WriteFile(path, []byte(`{"websites": [{"name": "GitHub", "base_url": "https://github.com/{}"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if sites, err := LoadSiteDatabase(ctx, path); err != nil || len(sites) != 1 || sites[0].Name != "GitHub" {
+		t.Errorf("file %s: %+v, %v", path, sites, err)
+	}
+
+	// Ошибка разбора называет источник, чтобы было понятно, какую базу чинить
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`{"sites": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSiteDatabase(ctx, broken); err == nil || !strings.Contains(err.Error(), broken) {
+		t.Errorf("broken file: error %v, want it to name %s", err, broken)
+	}
+	if _, err := LoadSiteDatabase(ctx, filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("missing file: error %v, want not exist", err)
+	}
+	if _, err := LoadSiteDatabase(ctx, "http://sites.example/data.json"); err == nil || !strings.Contains(err.Error(), "only https") {
+		t.Errorf("http source: error %v, want only https URLs", err)
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовок, в котором Mini App передает tg.initData
+const initDataHeader = "X-Telegram-Init-Data"
+
+// Окно свежести auth_date по умолчанию
+const defaultInitDataMaxAge = 24 * time.Hour
+
+// TelegramUser — пользователь Telegram из проверенного initData
+type TelegramUser struct {
+	ID           int64  `json:"id"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+	IsPremium    bool   `json:"is_premium,omitempty"`
+}
+
+var (
+	errInitDataMissing = errors.New("init data is missing")
+	errInitDataHash    = errors.New("init data hash mismatch")
+	errInitDataExpired = errors.New("init data is expired")
+	errInitDataInvalid = errors.New("init data is malformed")
+)
+
+type contextKey int
+
+const (
+	telegramUserKey contextKey = iota
+	apiKeyKey
+	proxyPolicyKey
+	apiVersionKey
+	mediaTypeKey
+)
+
+// TelegramUserFromContext возвращает пользователя, прикрепленного requireTelegramAuth
+func TelegramUserFromContext(ctx context.Context) (*TelegramUser, bool) {
+	user, ok := ctx.Value(telegramUserKey).(*TelegramUser)
+	return user, ok && user != nil
+}
+
+// validateInitData проверяет подпись initData (HMAC-SHA256 от data-check-string
+// с ключом HMAC_SHA256("WebAppData", botToken)) и свежесть auth_date.
+func validateInitData(initData, botToken string, maxAge time.Duration, now time.Time) (*TelegramUser, error) {
+	if initData == "" {
+		return nil, errInitDataMissing
+	}
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return nil, errInitDataInvalid
+	}
+	hash := values.Get("hash")
+	if hash == "" {
+		return nil, errInitDataInvalid
+	}
+
+	// data-check-string: все поля, кроме hash, отсортированные по ключу, через \n
+	pairs := make([]string, 0, len(values))
+	for key := range values {
+		if key == "hash" {
+			continue
+		}
+		pairs = append(pairs, key+"="+values.Get(key))
+	}
+	sort.Strings(pairs)
+	dataCheckString := strings.Join(pairs, "\n")
+
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(dataCheckString))
+
+	expected, err := hex.DecodeString(hash)
+	if err != nil || !hmac.Equal(mac.Sum(nil), expected) {
+		return nil, errInitDataHash
+	}
+
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return nil, errInitDataInvalid
+	}
+	age := now.Sub(time.Unix(authDate, 0))
+	if maxAge > 0 && (age > maxAge || age < -time.Minute) {
+		return nil, errInitDataExpired
+	}
+
+	var user TelegramUser
+	if err := json.Unmarshal([]byte(values.Get("user")), &user); err != nil || user.ID == 0 {
+		return nil, errInitDataInvalid
+	}
+	return &user, nil
+}
+
+// requireTelegramAuth пропускает только запросы с валидным initData Mini App
+// и кладет пользователя Telegram в контекст запроса.
+func requireTelegramAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv("TELEGRAM_BOT_TOKEN")
+		if token == "" {
+			log.Println("Error: TELEGRAM_BOT_TOKEN environment variable not set")
+			http.Error(w, "Server configuration error", http.StatusInternalServerError)
+			return
+		}
+
+		user, err := validateInitData(r.Header.Get(initDataHeader), token, time.Duration(Settings().Telegram.InitDataMaxAge), time.Now())
+		if err != nil {
+			log.Printf("Rejected request to %s: %v", r.URL.Path, err)
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), telegramUserKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBotToken = "123456:test-token"
+
+// signInitData подписывает поля так же, как Telegram подписывает initData Mini App
+func signInitData(token string, fields url.Values) string {
+	pairs := make([]string, 0, len(fields))
+	for key := range fields {
+		pairs = append(pairs, key+"="+fields.Get(key))
+	}
+	sort.Strings(pairs)
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(token))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+
+	signed := url.Values{}
+	for key := range fields {
+		signed.Set(key, fields.Get(key))
+	}
+	signed.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return signed.Encode()
+}
+
+func initDataFields(authDate time.Time, user string) url.Values {
+	return url.Values{
+		"auth_date": {strconv.FormatInt(authDate.Unix(), 10)},
+		"query_id":  {"AAHdF6IQAAAAAN0XohDhrOrc"},
+		"user":      {user},
+	}
+}
+
+func TestValidateInitData(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alice := `{"id":42,"first_name":"Alice","username":"alice"}`
+	valid := signInitData(testBotToken, initDataFields(now.Add(-time.Hour), alice))
+
+	tampered, _ := url.ParseQuery(valid)
+	tampered.Set("user", `{"id":43,"first_name":"Mallory"}`)
+	badHash, _ := url.ParseQuery(valid)
+	badHash.Set("hash", strings.Repeat("0", 64))
+
+	tests := []struct {
+		name     string
+		initData string
+		token    string
+		want     error
+	}{
+		{"valid", valid, testBotToken, nil},
+		{"empty", "", testBotToken, errInitDataMissing},
+		{"no hash", "auth_date=1&user=%7B%7D", testBotToken, errInitDataInvalid},
+		{"other bot", valid, "654321:other-token", errInitDataHash},
+		{"tampered field", tampered.Encode(), testBotToken, errInitDataHash},
+		{"tampered hash", badHash.Encode(), testBotToken, errInitDataHash},
+		{"hash not hex", strings.Replace(valid, "hash=", "hash=zz", 1), testBotToken, errInitDataHash},
+		{"expired", signInitData(testBotToken, initDataFields(now.Add(-25*time.Hour), alice)), testBotToken, errInitDataExpired},
+		{"from the future", signInitData(testBotToken, initDataFields(now.Add(2*time.Minute), alice)), testBotToken, errInitDataExpired},
+		{"clock skew", signInitData(testBotToken, initDataFields(now.Add(30*time.Second), alice)), testBotToken, nil},
+		{"bad auth_date", signInitData(testBotToken, url.Values{"auth_date": {"yesterday"}, "user": {alice}}), testBotToken, errInitDataInvalid},
+		{"no user", signInitData(testBotToken, url.Values{"auth_date": {strconv.FormatInt(now.Unix(), 10)}}), testBotToken, errInitDataInvalid},
+		{"user not JSON", signInitData(testBotToken, initDataFields(now, "alice")), testBotToken, errInitDataInvalid},
+		{"user without id", signInitData(testBotToken, initDataFields(now, `{"first_name":"Alice"}`)), testBotToken, errInitDataInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := validateInitData(tt.initData, tt.token, 24*time.Hour, now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && (user == nil || user.ID != 42 || user.Username != "alice") {
+				t.Errorf("user = %+v, want alice with id 42", user)
+			}
+		})
+	}
+}
+
+func TestValidateInitDataWithoutMaxAge(t *testing.T) {
+	old := signInitData(testBotToken, initDataFields(time.Unix(0, 0), `{"id":42}`))
+	if _, err := validateInitData(old, testBotToken, 0, time.Now()); err != nil {
+		t.Errorf("max age 0 must not check auth_date: %v", err)
+	}
+}
+
+func TestRequireTelegramAuth(t *testing.T) {
+	t.Setenv("TELEGRAM_BOT_TOKEN", testBotToken)
+	var seen *TelegramUser
+	handler := requireTelegramAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = TelegramUserFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		initData string
+		status   int
+	}{
+		{"valid", signInitData(testBotToken, initDataFields(time.Now(), `{"id":42,"first_name":"Alice"}`)), http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"forged", signInitData("654321:other-token", initDataFields(time.Now(), `{"id":42}`)), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			r := httptest.NewRequest("GET", "/search?username=alice", nil)
+			if tt.initData != "" {
+				r.Header.Set(initDataHeader, tt.initData)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusOK && (seen == nil || seen.ID != 42) {
+				t.Errorf("user in context = %+v, want id 42", seen)
+			}
+			if tt.status != http.StatusOK && seen != nil {
+				t.Error("rejected request reached the handler")
+			}
+		})
+	}
+
+	t.Setenv("TELEGRAM_BOT_TOKEN", "")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/search", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("without a bot token: status %d, want 500", w.Code)
+	}
+}
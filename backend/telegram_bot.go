@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Базовый URL Bot API по умолчанию; переопределяется telegram.api_url
+const defaultTelegramAPIURL = "https://api.telegram.org"
+
+// telegramAPIURL возвращает базовый URL Bot API без завершающего слеша
+func telegramAPIURL() string {
+	return strings.TrimRight(Settings().Telegram.APIURL, "/")
+}
+
+// Update — входящее обновление Bot API (только используемые поля)
+type Update struct {
+	UpdateID    int64        `json:"update_id"`
+	Message     *Message     `json:"message,omitempty"`
+	InlineQuery *InlineQuery `json:"inline_query,omitempty"`
+}
+
+// Message — входящее сообщение (только используемые поля)
+type Message struct {
+	MessageID int64         `json:"message_id"`
+	From      *TelegramUser `json:"from,omitempty"`
+	Chat      Chat          `json:"chat"`
+	Text      string        `json:"text"`
+}
+
+// Chat — чат, из которого пришло сообщение
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// InlineQuery — запрос вида "@bot username"
+type InlineQuery struct {
+	ID    string        `json:"id"`
+	From  *TelegramUser `json:"from"`
+	Query string        `json:"query"`
+}
+
+// InlineQueryResultArticle — текстовый результат inline-режима
+type InlineQueryResultArticle struct {
+	Type                string              `json:"type"`
+	ID                  string              `json:"id"`
+	Title               string              `json:"title"`
+	Description         string              `json:"description,omitempty"`
+	URL                 string              `json:"url,omitempty"`
+	InputMessageContent InputMessageContent `json:"input_message_content"`
+}
+
+// InputMessageContent — сообщение, которое отправляется при выборе результата
+type InputMessageContent struct {
+	MessageText string `json:"message_text"`
+}
+
+// apiError — ответ Bot API с ok=false
+type apiError struct {
+	Method      string
+	Code        int
+	Description string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %d %s", e.Method, e.Code, e.Description)
+}
+
+// botAPI — минимальный клиент Telegram Bot API
+type botAPI struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newBotAPI(token string) *botAPI {
+	return &botAPI{
+		baseURL: telegramAPIURL(),
+		token:   token,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// sendMessage отправляет текст в чат ответом на сообщение replyTo (0 — без ответа)
+func (b *botAPI) sendMessage(ctx context.Context, chatID, replyTo int64, text string) error {
+	params := map[string]interface{}{
+		"chat_id":              chatID,
+		"text":                 text,
+		"link_preview_options": map[string]bool{"is_disabled": true},
+	}
+	if replyTo != 0 {
+		params["reply_parameters"] = map[string]interface{}{"message_id": replyTo, "allow_sending_without_reply": true}
+	}
+	return b.call(ctx, "sendMessage", params, nil)
+}
+
+// call вызывает метод Bot API с JSON-параметрами и раскладывает поле result в out
+func (b *botAPI) call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/bot%s/%s", b.baseURL, b.token, method), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoSearchBot/1.0")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var apiResp struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+	}
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return fmt.Errorf("%s: parse response: %w", method, err)
+	}
+	if !apiResp.OK {
+		return &apiError{Method: method, Code: apiResp.ErrorCode, Description: apiResp.Description}
+	}
+	if out != nil {
+		return json.Unmarshal(apiResp.Result, out)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+)
+
+// TelegramResult — результат проверки имени в Telegram через getChat
+type TelegramResult struct {
+	Found        bool   `json:"found"`
+	Type         string `json:"type,omitempty"` // private, bot, group, supergroup, channel
+	ID           int64  `json:"id,omitempty"`
+	Title        string `json:"title,omitempty"` // название чата или имя пользователя
+	Username     string `json:"username,omitempty"`
+	Description  string `json:"description,omitempty"` // описание чата или bio пользователя
+	LinkedChatID int64  `json:"linked_chat_id,omitempty"`
+	PhotoFileID  string `json:"photo_file_id,omitempty"`
+	MemberCount  *int   `json:"member_count,omitempty"` // только если getChatMemberCount разрешен
+	// Error заполняется при сбое API или сети; в этом случае Found ничего не говорит
+	Error string `json:"error,omitempty"`
+}
+
+// chatFullInfo — поля ответа getChat, которые мы используем
+type chatFullInfo struct {
+	ID           int64  `json:"id"`
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	Username     string `json:"username"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Description  string `json:"description"`
+	Bio          string `json:"bio"`
+	LinkedChatID int64  `json:"linked_chat_id"`
+	Photo        *struct {
+		BigFileID string `json:"big_file_id"`
+	} `json:"photo"`
+}
+
+// lookupChat проверяет @username через getChat и, для групп и каналов, getChatMemberCount
+func (b *botAPI) lookupChat(ctx context.Context, username string) *TelegramResult {
+	var chat chatFullInfo
+	err := b.call(ctx, "getChat", map[string]string{"chat_id": "@" + username}, &chat)
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && isChatNotFound(apiErr) {
+			return &TelegramResult{Found: false}
+		}
+		log.Printf("Error checking Telegram chat @%s: %v", username, err)
+		return &TelegramResult{Found: false, Error: err.Error()}
+	}
+
+	result := &TelegramResult{
+		Found:        true,
+		Type:         chat.Type,
+		ID:           chat.ID,
+		Title:        chat.Title,
+		Username:     chat.Username,
+		Description:  chat.Description,
+		LinkedChatID: chat.LinkedChatID,
+	}
+	if chat.Type == "private" {
+		result.Title = strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+		result.Description = chat.Bio
+		// Telegram требует, чтобы имена ботов оканчивались на "bot"
+		if strings.HasSuffix(strings.ToLower(chat.Username), "bot") {
+			result.Type = "bot"
+		}
+	}
+	if chat.Photo != nil {
+		result.PhotoFileID = chat.Photo.BigFileID
+	}
+
+	if chat.Type == "group" || chat.Type == "supergroup" || chat.Type == "channel" {
+		var count int
+		if err := b.call(ctx, "getChatMemberCount", map[string]string{"chat_id": "@" + username}, &count); err == nil {
+			result.MemberCount = &count
+		}
+	}
+	return result
+}
+
+// isChatNotFound отличает "чата нет" от прочих ошибок API (токен, лимиты, сбои)
+func isChatNotFound(err *apiError) bool {
+	if err.Code != 400 {
+		return false
+	}
+	description := strings.ToLower(err.Description)
+	return strings.Contains(description, "chat not found") ||
+		strings.Contains(description, "username_invalid") ||
+		strings.Contains(description, "user not found")
+}
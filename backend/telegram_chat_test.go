@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chatAPI — Bot API, который на getChat и getChatMemberCount отвечает из таблицы по chat_id
+func chatAPI(t *testing.T, chats map[string]interface{}, counts map[string]int) *botAPI {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		json.NewDecoder(r.Body).Decode(&params)
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		chatID := params["chat_id"]
+
+		switch method {
+		case "getChat":
+			switch chat := chats[chatID].(type) {
+			case nil:
+				json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"})
+			case int:
+				// Число вместо чата — код ошибки API
+				w.WriteHeader(chat)
+				json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": chat, "description": http.StatusText(chat)})
+			default:
+				json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": chat})
+			}
+		case "getChatMemberCount":
+			count, ok := counts[chatID]
+			if !ok {
+				json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": 400, "description": "Bad Request: member list is inaccessible"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": count})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &botAPI{baseURL: server.URL, token: testBotToken, client: server.Client()}
+}
+
+func TestLookupChat(t *testing.T) {
+	bot := chatAPI(t, map[string]interface{}{
+		"@alice":      map[string]interface{}{"id": 1, "type": "private", "username": "alice", "first_name": "Alice", "last_name": "Smith", "bio": "hi", "photo": map[string]string{"big_file_id": "photo1"}},
+		"@helper_bot": map[string]interface{}{"id": 2, "type": "private", "username": "Helper_Bot", "first_name": "Helper"},
+		"@news":       map[string]interface{}{"id": -100, "type": "channel", "username": "news", "title": "News", "description": "daily", "linked_chat_id": -200},
+		"@chat":       map[string]interface{}{"id": -300, "type": "supergroup", "username": "chat", "title": "Chat"},
+		"@limited":    http.StatusTooManyRequests,
+		"@revoked":    http.StatusUnauthorized,
+	}, map[string]int{"@news": 1500})
+
+	tests := []struct {
+		username string
+		want     TelegramResult
+		count    int // -1 — числа участников нет
+	}{
+		{"alice", TelegramResult{Found: true, Type: "private", ID: 1, Title: "Alice Smith", Username: "alice", Description: "hi", PhotoFileID: "photo1"}, -1},
+		{"helper_bot", TelegramResult{Found: true, Type: "bot", ID: 2, Title: "Helper", Username: "Helper_Bot"}, -1},
+		{"news", TelegramResult{Found: true, Type: "channel", ID: -100, Title: "News", Username: "news", Description: "daily", LinkedChatID: -200}, 1500},
+		// getChatMemberCount запрещен — чат найден, но без числа участников
+		{"chat", TelegramResult{Found: true, Type: "supergroup", ID: -300, Title: "Chat", Username: "chat"}, -1},
+		{"nobody", TelegramResult{Found: false}, -1},
+	}
+	for _, tt := range tests {
+		got := bot.lookupChat(context.Background(), tt.username)
+		count := -1
+		if got.MemberCount != nil {
+			count = *got.MemberCount
+		}
+		if count != tt.count {
+			t.Errorf("@%s: member count %d, want %d", tt.username, count, tt.count)
+		}
+		got.MemberCount = nil
+		if *got != tt.want {
+			t.Errorf("@%s: %+v, want %+v", tt.username, *got, tt.want)
+		}
+	}
+
+	// Сбой API — это не "не найдено": ошибка попадает в результат
+	for _, username := range []string{"limited", "revoked"} {
+		if got := bot.lookupChat(context.Background(), username); got.Found || got.Error == "" {
+			t.Errorf("@%s: %+v, want an error instead of not found", username, *got)
+		}
+	}
+}
+
+func TestSearchBoundsTelegramBySiteTimeout(t *testing.T) {
+	profiles := httptest.NewServer(http.NotFoundHandler())
+	defer profiles.Close()
+	useTestSites(t, statusSite("TimeoutSite", profiles.URL+"/{}"))
+
+	// getChat отвечает дольше, чем длится поиск
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer api.Close()
+	bot := &botAPI{baseURL: api.URL, token: testBotToken, client: &http.Client{Timeout: 10 * time.Second}}
+
+	cfg := Settings()
+	saved := cfg.Search.SiteTimeout
+	cfg.Search.SiteTimeout = Duration(100 * time.Millisecond)
+	t.Cleanup(func() { cfg.Search.SiteTimeout = saved })
+
+	start := time.Now()
+	result := searchUsername(context.Background(), bot, "alice", SearchOptions{})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("search took %v, want it bounded by site_timeout", elapsed)
+	}
+	if result.Telegram == nil || result.Telegram.Error == "" {
+		t.Errorf("telegram %+v, want a timeout error", result.Telegram)
+	}
+}
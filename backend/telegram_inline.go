@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Внутренний дедлайн на поиск для inline-запроса: Telegram ждет ответ недолго
+	inlineSearchTimeout = 5 * time.Second
+	// Сколько хранить готовый ответ, чтобы повторные нажатия не запускали новый поиск
+	inlineCacheTTL = 5 * time.Minute
+	// Короче этого не сканируем: пользователь еще печатает
+	inlineMinQueryLength = 3
+	// Лимит длины текста сообщения в Telegram
+	telegramMessageLimit = 4096
+	// Telegram принимает не больше 50 результатов на inline-запрос
+	inlineMaxResults = 50
+)
+
+// inlineCacheEntry — результат поиска (готовый или выполняющийся)
+type inlineCacheEntry struct {
+	done    chan struct{}
+	result  SearchResult
+	expires time.Time
+}
+
+// inlineCache хранит недавние результаты и склеивает одновременные запросы одного имени
+type inlineCache struct {
+	mu      sync.Mutex
+	entries map[string]*inlineCacheEntry
+	ttl     time.Duration
+}
+
+var inlineResults = &inlineCache{entries: make(map[string]*inlineCacheEntry), ttl: inlineCacheTTL}
+
+// get возвращает результат из кэша или запускает search; ok=false, если ctx истек раньше
+func (c *inlineCache) get(ctx context.Context, key string, search func() SearchResult) (SearchResult, bool) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, exists := c.entries[key]
+	if exists && !entry.expires.IsZero() && now.After(entry.expires) {
+		exists = false
+	}
+	if !exists {
+		for k, e := range c.entries {
+			if !e.expires.IsZero() && now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		entry = &inlineCacheEntry{done: make(chan struct{})}
+		c.entries[key] = entry
+		go func() {
+			result := search()
+			c.mu.Lock()
+			entry.result = result
+			entry.expires = time.Now().Add(c.ttl)
+			c.mu.Unlock()
+			close(entry.done)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-entry.done:
+		return entry.result, true
+	case <-ctx.Done():
+		return SearchResult{}, false
+	}
+}
+
+// has — есть ли по key готовый или выполняющийся поиск, который get отдаст без нового запуска
+func (c *inlineCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, exists := c.entries[key]
+	return exists && (entry.expires.IsZero() || time.Now().Before(entry.expires))
+}
+
+// handleTelegramWebhook принимает обновления от Telegram и отвечает на inline-запросы
+func handleTelegramWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Секрет из setWebhook(secret_token=...), чтобы чужие не слали нам обновления.
+	// Без секрета вебхук закрыт: иначе любой мог бы запускать поиски от чужого user id.
+	secret := os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+	if secret == "" {
+		log.Println("Error: TELEGRAM_WEBHOOK_SECRET environment variable not set, webhook is disabled")
+		http.Error(w, "Webhook is disabled: TELEGRAM_WEBHOOK_SECRET is not set", http.StatusServiceUnavailable)
+		return
+	}
+	got := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token := os.Getenv("TELEGRAM_BOT_TOKEN")
+	if token == "" {
+		log.Println("Error: TELEGRAM_BOT_TOKEN environment variable not set")
+		http.Error(w, "Server configuration error", http.StatusInternalServerError)
+		return
+	}
+
+	var update Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid update", http.StatusBadRequest)
+		return
+	}
+
+	if update.InlineQuery != nil {
+		if err := answerInlineQuery(r.Context(), newBotAPI(token), update.InlineQuery); err != nil {
+			log.Printf("Error answering inline query %s: %v", update.InlineQuery.ID, err)
+		}
+	}
+
+	// Telegram повторяет доставку при не-200, поэтому ошибки только логируем
+	w.WriteHeader(http.StatusOK)
+}
+
+// answerInlineQuery выполняет (или берет из кэша) поиск и вызывает answerInlineQuery
+func answerInlineQuery(ctx context.Context, bot *botAPI, query *InlineQuery) error {
+	username := strings.TrimPrefix(strings.TrimSpace(query.Query), "@")
+
+	results := []InlineQueryResultArticle{}
+	cacheTime := int(inlineCacheTTL.Seconds())
+	if len(username) >= inlineMinQueryLength && !strings.ContainsAny(username, " /?#&") {
+		key := strings.ToLower(username)
+		// Квота — только за новый поиск: ответ из кэша сайты не нагружает
+		if query.From != nil && !inlineResults.has(key) {
+			if exceeded := checkQuota(fmt.Sprintf("tg:%d", query.From.ID), ""); exceeded != nil {
+				return answerInlineQuotaExceeded(ctx, bot, query, exceeded)
+			}
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, inlineSearchTimeout)
+		result, ok := inlineResults.get(waitCtx, key, func() SearchResult {
+			// Поиск не привязан к HTTP-запросу: его результат пригодится следующим нажатиям
+			searchCtx, cancelSearch := context.WithTimeout(context.Background(), inlineSearchTimeout)
+			defer cancelSearch()
+			return searchUsername(searchCtx, bot, username, SearchOptions{})
+		})
+		cancel()
+		if ok {
+			results = buildInlineResults(result)
+		} else {
+			// Поиск не успел: не даем Telegram закэшировать пустой ответ
+			cacheTime = 0
+		}
+	}
+
+	params := map[string]interface{}{
+		"inline_query_id": query.ID,
+		"results":         results,
+		"cache_time":      cacheTime,
+	}
+	return bot.call(ctx, "answerInlineQuery", params, nil)
+}
+
+// answerInlineQuotaExceeded отвечает одной статьей о лимите; без кэша, чтобы после сброса лимита поиск заработал
+func answerInlineQuotaExceeded(ctx context.Context, bot *botAPI, query *InlineQuery, exceeded *quotaExceeded) error {
+	text := "Превышен лимит запросов. Повторите после " + exceeded.RetryAt.UTC().Format("15:04 UTC")
+	params := map[string]interface{}{
+		"inline_query_id": query.ID,
+		"results": []InlineQueryResultArticle{{
+			Type:                "article",
+			ID:                  "quota",
+			Title:               text,
+			InputMessageContent: InputMessageContent{MessageText: text},
+		}},
+		"cache_time":  0,
+		"is_personal": true,
+	}
+	return bot.call(ctx, "answerInlineQuery", params, nil)
+}
+
+// searchSummary — одна строка с итогом поиска
+func searchSummary(result SearchResult) string {
+	return fmt.Sprintf("%s: найден на %d из %d источников", result.Username, len(result.FoundOn), result.TotalSitesChecked)
+}
+
+// searchResultText — итог поиска и список ссылок на профили в пределах лимита сообщения
+func searchResultText(result SearchResult) string {
+	messageText := searchSummary(result)
+	for _, found := range result.Results {
+		line := fmt.Sprintf("• %s — %s", found.Site, found.URL)
+		if len(messageText)+len(line)+1 > telegramMessageLimit {
+			break
+		}
+		messageText += "\n" + line
+	}
+	return messageText
+}
+
+// buildInlineResults: сводная статья + по одной статье на каждый найденный профиль
+func buildInlineResults(result SearchResult) []InlineQueryResultArticle {
+	articles := []InlineQueryResultArticle{{
+		Type:                "article",
+		ID:                  "summary",
+		Title:               searchSummary(result),
+		Description:         strings.Join(result.FoundOn, ", "),
+		InputMessageContent: InputMessageContent{MessageText: searchResultText(result)},
+	}}
+
+	for i, found := range result.Results {
+		if len(articles) >= inlineMaxResults {
+			break
+		}
+		siteName, link := found.Site, found.URL
+		articles = append(articles, InlineQueryResultArticle{
+			Type:                "article",
+			ID:                  fmt.Sprintf("site-%d", i),
+			Title:               siteName,
+			Description:         link,
+			URL:                 link,
+			InputMessageContent: InputMessageContent{MessageText: fmt.Sprintf("%s — %s: %s", result.Username, siteName, link)},
+		})
+	}
+	return articles
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelegramWebhook(t *testing.T) {
+	profiles := httptest.NewServer(http.NotFoundHandler())
+	defer profiles.Close()
+	useTestSites(t, statusSite("WebhookSite", profiles.URL+"/{}"))
+
+	bot := &fakeBotAPI{sent: make(chan botCall, 1)}
+	api := httptest.NewServer(bot)
+	defer api.Close()
+	cfg := Settings()
+	apiURL := cfg.Telegram.APIURL
+	cfg.Telegram.APIURL = api.URL
+	t.Cleanup(func() { cfg.Telegram.APIURL = apiURL })
+	t.Setenv("TELEGRAM_BOT_TOKEN", testBotToken)
+
+	// Поиск по inline-запросу списывает квоту с from.id, поэтому id у каждого запуска свой
+	update := fmt.Sprintf(`{"update_id": 1, "inline_query": {"id": "q1", "from": {"id": %d, "first_name": "Alice"}, "query": "webhook_alice"}}`, time.Now().UnixNano())
+
+	tests := []struct {
+		name   string
+		secret string // TELEGRAM_WEBHOOK_SECRET
+		header string // X-Telegram-Bot-Api-Secret-Token
+		method string
+		body   string
+		status int
+		answer bool
+	}{
+		{"no secret configured", "", "", "POST", update, http.StatusServiceUnavailable, false},
+		{"no secret configured, forged header", "", "anything", "POST", update, http.StatusServiceUnavailable, false},
+		{"missing header", "s3cret", "", "POST", update, http.StatusUnauthorized, false},
+		{"wrong secret", "s3cret", "guess", "POST", update, http.StatusUnauthorized, false},
+		{"GET", "s3cret", "s3cret", "GET", "", http.StatusMethodNotAllowed, false},
+		{"bad JSON", "s3cret", "s3cret", "POST", "{", http.StatusBadRequest, false},
+		{"inline query", "s3cret", "s3cret", "POST", update, http.StatusOK, true},
+		{"no inline query", "s3cret", "s3cret", "POST", `{"update_id": 2}`, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TELEGRAM_WEBHOOK_SECRET", tt.secret)
+			r := httptest.NewRequest(tt.method, "/telegram/webhook", strings.NewReader(tt.body))
+			if tt.header != "" {
+				r.Header.Set("X-Telegram-Bot-Api-Secret-Token", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handleTelegramWebhook(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			select {
+			case call := <-bot.sent:
+				if !tt.answer {
+					t.Errorf("unexpected %s", call.Method)
+				} else if call.Method != "answerInlineQuery" {
+					t.Errorf("bot called %s, want answerInlineQuery", call.Method)
+				}
+			default:
+				if tt.answer {
+					t.Error("inline query was not answered")
+				}
+			}
+		})
+	}
+}
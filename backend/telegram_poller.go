@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Таймаут long polling по умолчанию
+	defaultPollTimeout = 30 * time.Second
+	// Пауза после ошибки getUpdates
+	pollRetryDelay = 3 * time.Second
+	// Сколько ждем поиск по команде /search
+	commandSearchTimeout = 10 * time.Second
+)
+
+// Poller — режим long polling (getUpdates) для развертываний, куда не доходят вебхуки
+type Poller struct {
+	Token string
+	// APIURL — базовый URL Bot API; пусто — TELEGRAM_API_URL или api.telegram.org
+	APIURL string
+	// Timeout — таймаут long polling; ноль — 30 секунд
+	Timeout time.Duration
+}
+
+// Run опрашивает getUpdates до отмены ctx, затем дожидается начатых обработчиков
+// и подтверждает обработанные обновления, чтобы после перезапуска они не пришли снова.
+func (p *Poller) Run(ctx context.Context) error {
+	if p.Token == "" {
+		return errors.New("poller: bot token is required")
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultPollTimeout
+	}
+	// Без вебхука Handler не вызывается, поэтому базу сайтов загружаем сами
+	loadSites()
+
+	bot := newBotAPI(p.Token)
+	if p.APIURL != "" {
+		bot.baseURL = strings.TrimRight(p.APIURL, "/")
+	}
+	// Клиент должен ждать дольше, чем Telegram держит запрос
+	bot.client = &http.Client{Timeout: timeout + 10*time.Second}
+
+	var wg sync.WaitGroup
+	var offset int64
+	log.Printf("Poller started (api=%s, timeout=%s)", bot.baseURL, timeout)
+
+	for ctx.Err() == nil {
+		var updates []Update
+		params := map[string]interface{}{
+			"offset":          offset,
+			"timeout":         int(timeout.Seconds()),
+			"allowed_updates": []string{"message", "inline_query"},
+		}
+		if err := bot.call(ctx, "getUpdates", params, &updates); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("Error polling updates: %v", err)
+			select {
+			case <-time.After(pollRetryDelay):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			if update.UpdateID >= offset {
+				offset = update.UpdateID + 1
+			}
+			wg.Add(1)
+			go func(update Update) {
+				defer wg.Done()
+				// Обработчики не отменяются вместе с ctx: начатый поиск доводим до ответа
+				handleBotUpdate(context.Background(), bot, update)
+			}(update)
+		}
+	}
+
+	log.Println("Poller stopping, waiting for in-flight updates")
+	wg.Wait()
+
+	if offset != 0 {
+		ackCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		params := map[string]interface{}{"offset": offset, "timeout": 0}
+		if err := bot.call(ackCtx, "getUpdates", params, nil); err != nil {
+			log.Printf("Error confirming offset %d: %v", offset, err)
+		}
+	}
+	return nil
+}
+
+// handleBotUpdate обрабатывает одно обновление: команды /search и inline-запросы
+func handleBotUpdate(ctx context.Context, bot *botAPI, update Update) {
+	if update.InlineQuery != nil {
+		if err := answerInlineQuery(ctx, bot, update.InlineQuery); err != nil {
+			log.Printf("Error answering inline query %s: %v", update.InlineQuery.ID, err)
+		}
+		return
+	}
+	if update.Message == nil {
+		return
+	}
+
+	username, ok := parseSearchCommand(update.Message.Text)
+	if !ok {
+		return
+	}
+	msg := update.Message
+	if username == "" {
+		if err := bot.sendMessage(ctx, msg.Chat.ID, msg.MessageID, "Использование: /search <username>"); err != nil {
+			log.Printf("Error sending usage to chat %d: %v", msg.Chat.ID, err)
+		}
+		return
+	}
+
+	if msg.From != nil {
+		if exceeded := checkQuota(fmt.Sprintf("tg:%d", msg.From.ID), ""); exceeded != nil {
+			text := "Превышен лимит запросов. Повторите после " + exceeded.RetryAt.UTC().Format("15:04 UTC")
+			if err := bot.sendMessage(ctx, msg.Chat.ID, msg.MessageID, text); err != nil {
+				log.Printf("Error sending quota notice to chat %d: %v", msg.Chat.ID, err)
+			}
+			return
+		}
+	}
+
+	searchCtx, cancel := context.WithTimeout(ctx, commandSearchTimeout)
+	result := searchUsername(searchCtx, bot, username, SearchOptions{})
+	cancel()
+
+	text := searchResultText(result)
+	if len(result.FoundOn) == 0 && result.Error != "" {
+		text = result.Username + ": " + result.Error
+	}
+	if err := bot.sendMessage(ctx, msg.Chat.ID, msg.MessageID, text); err != nil {
+		log.Printf("Error sending search result to chat %d: %v", msg.Chat.ID, err)
+	}
+}
+
+// parseSearchCommand разбирает "/search username" и "/search@bot username";
+// ok=false, если это не команда /search
+func parseSearchCommand(text string) (username string, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", false
+	}
+	command := fields[0]
+	if at := strings.Index(command, "@"); at >= 0 {
+		command = command[:at]
+	}
+	if command != "/search" {
+		return "", false
+	}
+	if len(fields) < 2 {
+		return "", true
+	}
+	return strings.TrimPrefix(fields[1], "@"), true
+}
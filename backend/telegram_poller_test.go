@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBotAPI — Bot API на httptest: отдает updates одним пакетом и пересылает в sent
+// вызовы sendMessage и answerInlineQuery
+type fakeBotAPI struct {
+	mu      sync.Mutex
+	updates []Update
+	sent    chan botCall
+}
+
+// botCall — метод Bot API и его параметры
+type botCall struct {
+	Method string
+	Params map[string]interface{}
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	var params map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&params)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch method {
+	case "getUpdates":
+		f.mu.Lock()
+		updates := f.updates
+		f.updates = nil
+		f.mu.Unlock()
+		if len(updates) == 0 {
+			// Как long polling: держим запрос, пока поллер не остановят
+			select {
+			case <-r.Context().Done():
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": updates})
+	case "sendMessage", "answerInlineQuery":
+		f.sent <- botCall{Method: method, Params: params}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": map[string]interface{}{}})
+	case "getChat":
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestPollerAnswersSearchCommand(t *testing.T) {
+	profiles := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/alice" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer profiles.Close()
+	useTestSites(t,
+		statusSite("PollerHit", profiles.URL+"/{}"),
+		statusSite("PollerMiss", profiles.URL+"/nobody/{}"),
+	)
+
+	bot := &fakeBotAPI{
+		sent: make(chan botCall, 1),
+		updates: []Update{{
+			UpdateID: 7,
+			Message: &Message{
+				MessageID: 1,
+				From:      &TelegramUser{ID: 42},
+				Chat:      Chat{ID: 100, Type: "private"},
+				Text:      "/search @alice",
+			},
+		}},
+	}
+	api := httptest.NewServer(bot)
+	defer api.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- (&Poller{Token: "test", APIURL: api.URL, Timeout: time.Second}).Run(ctx)
+	}()
+
+	select {
+	case call := <-bot.sent:
+		msg := call.Params
+		if msg["chat_id"].(float64) != 100 {
+			t.Errorf("chat_id = %v, want 100", msg["chat_id"])
+		}
+		text := msg["text"].(string)
+		if !strings.Contains(text, "PollerHit") || strings.Contains(text, "PollerMiss") {
+			t.Errorf("reply does not list exactly the found site:\n%s", text)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no sendMessage within 10s")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
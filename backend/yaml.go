@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Простой разбор YAML для файлов конфигурации без внешних зависимостей.
+// Поддерживаются вложенные словари, списки ("- x" и "[x, y]"), строки, числа,
+// true/false/null и комментарии. Якоря, многострочные строки и словари внутри
+// списков не поддерживаются — для сложных случаев используйте JSON.
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// parseSimpleYAML разбирает документ в map[string]interface{}, совместимый с encoding/json
+func parseSimpleYAML(data []byte) (map[string]interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(stripYAMLComment(raw), " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("yaml line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(raw) - len(text), text: text})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, next, err := parseYAMLBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("yaml line %d: unexpected indentation", lines[next].num)
+	}
+	doc, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("yaml: top level must be a mapping")
+	}
+	return doc, nil
+}
+
+// parseYAMLBlock разбирает словарь или список с отступом indent, начиная со строки i
+func parseYAMLBlock(lines []yamlLine, i, indent int) (interface{}, int, error) {
+	if strings.HasPrefix(lines[i].text, "- ") || lines[i].text == "-" {
+		var list []interface{}
+		for i < len(lines) && lines[i].indent == indent && strings.HasPrefix(lines[i].text, "-") {
+			item := strings.TrimSpace(strings.TrimPrefix(lines[i].text, "-"))
+			i++
+			if item != "" {
+				if isYAMLMappingEntry(item) {
+					return nil, i, fmt.Errorf("yaml line %d: mappings inside lists are not supported", lines[i-1].num)
+				}
+				list = append(list, parseYAMLScalar(item))
+				continue
+			}
+			if i >= len(lines) || lines[i].indent <= indent {
+				list = append(list, nil)
+				continue
+			}
+			value, next, err := parseYAMLBlock(lines, i, lines[i].indent)
+			if err != nil {
+				return nil, next, err
+			}
+			list = append(list, value)
+			i = next
+		}
+		return list, i, nil
+	}
+
+	mapping := map[string]interface{}{}
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		if !isYAMLMappingEntry(line.text) {
+			return nil, i, fmt.Errorf("yaml line %d: expected \"key: value\"", line.num)
+		}
+		key, rest, _ := strings.Cut(line.text, ":")
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		rest = strings.TrimSpace(rest)
+		i++
+
+		if rest != "" {
+			mapping[key] = parseYAMLScalar(rest)
+			continue
+		}
+		if i >= len(lines) || lines[i].indent < indent ||
+			(lines[i].indent == indent && !strings.HasPrefix(lines[i].text, "-")) {
+			mapping[key] = nil
+			continue
+		}
+		value, next, err := parseYAMLBlock(lines, i, lines[i].indent)
+		if err != nil {
+			return nil, next, err
+		}
+		mapping[key] = value
+		i = next
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, i, fmt.Errorf("yaml line %d: unexpected indentation", lines[i].num)
+	}
+	return mapping, i, nil
+}
+
+func isYAMLMappingEntry(text string) bool {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		end := strings.IndexByte(text[1:], text[0])
+		return end >= 0 && strings.HasPrefix(text[end+2:], ":")
+	}
+	key, rest, ok := strings.Cut(text, ":")
+	return ok && key != "" && (rest == "" || rest[0] == ' ')
+}
+
+// parseYAMLScalar превращает значение в строку, число, bool, nil или список "[a, b]"
+func parseYAMLScalar(text string) interface{} {
+	switch {
+	case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		list := []interface{}{}
+		if inner == "" {
+			return list
+		}
+		for _, item := range strings.Split(inner, ",") {
+			list = append(list, parseYAMLScalar(strings.TrimSpace(item)))
+		}
+		return list
+	case text == "{}":
+		return map[string]interface{}{}
+	case strings.HasPrefix(text, `"`):
+		if unquoted, err := strconv.Unquote(text); err == nil {
+			return unquoted
+		}
+		return strings.Trim(text, `"`)
+	case strings.HasPrefix(text, "'") && strings.HasSuffix(text, "'") && len(text) >= 2:
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'")
+	}
+
+	switch strings.ToLower(text) {
+	case "true", "yes", "on":
+		return true
+	case "false", "no", "off":
+		return false
+	case "null", "~":
+		return nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f
+	}
+	return text
+}
+
+// stripYAMLComment убирает "# ..." вне кавычек
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSimpleYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want map[string]interface{}
+	}{
+		{
+			name: "empty",
+			yaml: "# только комментарий\n---\n",
+			want: map[string]interface{}{},
+		},
+		{
+			name: "scalars",
+			yaml: "s: text\nq: \"a # b\"\nsq: 'it''s'\ni: 42\nf: 1.5\nb: yes\nn: ~\nurl: https://example.com/x#y  # комментарий\n",
+			want: map[string]interface{}{
+				"s": "text", "q": "a # b", "sq": "it's", "i": int64(42), "f": 1.5,
+				"b": true, "n": nil, "url": "https://example.com/x#y",
+			},
+		},
+		{
+			name: "nested",
+			yaml: "quota:\n  default:\n    daily: 50\n    burst_window: 1m\n  users:\n",
+			want: map[string]interface{}{
+				"quota": map[string]interface{}{
+					"default": map[string]interface{}{"daily": int64(50), "burst_window": "1m"},
+					"users":   nil,
+				},
+			},
+		},
+		{
+			name: "lists",
+			yaml: "flow: [a, 2, \"c\"]\nempty: []\nblock:\n  - x\n  - y\nsame_indent:\n- z\nobj: {}\n",
+			want: map[string]interface{}{
+				"flow":        []interface{}{"a", int64(2), "c"},
+				"empty":       []interface{}{},
+				"block":       []interface{}{"x", "y"},
+				"same_indent": []interface{}{"z"},
+				"obj":         map[string]interface{}{},
+			},
+		},
+		{
+			name: "quoted key",
+			yaml: "\"12345\": analyst\n",
+			want: map[string]interface{}{"12345": "analyst"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSimpleYAML([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("parseSimpleYAML: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSimpleYAML =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSimpleYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name, yaml, wantErr string
+	}{
+		{"tab indent", "a:\n\tb: 1\n", "line 2: tabs"},
+		{"bad indent", "a: 1\n  b: 2\n", "line 2: unexpected indentation"},
+		{"not a mapping", "a:\n  just text\n", "line 2: expected"},
+		{"mapping in list", "a:\n  - b: 1\n", "line 2: mappings inside lists"},
+		{"top-level list", "- a\n", "top level must be a mapping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSimpleYAML([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
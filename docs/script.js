@@ -235,9 +235,10 @@ function displayResults(data) {
                 
                 // Список утечек
                 const breachesList = document.createElement('ul');
-                data.breaches.forEach(breachInfo => {
+                data.breaches.forEach(breach => {
                     const item = document.createElement('li');
-                    item.textContent = breachInfo;
+                    const date = breach.breach_date ? ` (${breach.breach_date})` : '';
+                    item.textContent = `${breach.title || breach.name}${date} — источник: ${breach.sources.join(', ')}`;
                     breachesList.appendChild(item);
                 });
                 
@@ -335,9 +336,18 @@ async function performSearch() {
         const controller = new AbortController();
         const timeoutId = setTimeout(() => controller.abort(), 40000); // 40 секунд таймаут
         
+        // Адрес почты ищем в режиме email: имена из локальной части и источники вроде Gravatar
+        const isEmail = /^[^@\s]+@[^@\s]+\.[^@\s]+$/.test(username);
+        const query = isEmail
+            ? `type=email&email=${encodeURIComponent(username)}`
+            : `username=${encodeURIComponent(username)}`;
         const response = await fetch(
-            `${BACKEND_URL}/search?username=${encodeURIComponent(username)}`,
-            { signal: controller.signal }
+            `${BACKEND_URL}/search?${query}`,
+            {
+                signal: controller.signal,
+                // Подписанные данные Telegram для проверки на бэкенде
+                headers: { 'X-Telegram-Init-Data': tg.initData }
+            }
         );
         
         clearTimeout(timeoutId); // Очищаем таймаут если запрос завершился успешно